@@ -46,10 +46,35 @@ const (
 	JSON LogFormat = "json"
 	// NULL is null log format
 	Null LogFormat = "null"
-
-	HookFile   = "file"
-	HookSentry = "sentry"
-	HookRedis  = "redis"
+	// Console is colored and aligned log format for local development
+	Console LogFormat = "console"
+	// Logfmt is logfmt log format, `key=value` pairs
+	Logfmt LogFormat = "logfmt"
+	// Logstash is logstash json event format, with formatSettings `version`(v0 or v1), `application`, `hostname` and `default_hostname`
+	Logstash LogFormat = "logstash"
+	// ECS is Elastic Common Schema json format, with formatSettings `service_name`, `hostname` and `default_hostname`
+	ECS LogFormat = "ecs"
+	// GELF is GELF 1.1 json format of graylog, with formatSettings `hostname`
+	GELF LogFormat = "gelf"
+	// Access is access log format of middleware, with formatSettings `style`(combined, common or json)
+	Access LogFormat = "access"
+
+	// the formatSettings with this prefix are the custom names of the access fields, e.g. `field_remote_ip: client_ip`
+	accessFieldSettingPrefix = "field_"
+
+	HookFile          = "file"
+	HookSentry        = "sentry"
+	HookRedis         = "redis"
+	HookSyslog        = "syslog"
+	HookNet           = "net"
+	HookHTTP          = "http"
+	HookFluent        = "fluent"
+	HookKafka         = "kafka"
+	HookOTLP          = "otlp"
+	HookElasticsearch = "elasticsearch"
+	HookLoki          = "loki"
+	HookConsole       = "console"
+	HookGELF          = "gelf"
 )
 
 // LogHook is a struct holding settings for each enabled hook
@@ -83,9 +108,28 @@ type LogConfig struct {
 	FormatSettings map[string]string
 	Writer         LogWriter
 	Hooks          LogHooks
+	// RedactSettings enables the redaction if not nil, see hook.NewRedactor,
+	// the entries are redacted before all the hooks and the formatter
+	RedactSettings map[string]string
+	// SamplingSettings enables the sampling if not nil, see hook.SamplingLogHookBuilder,
+	// the entries are sampled before all the hooks and the formatter
+	SamplingSettings map[string]string
+	// ContextExtractors adds the fields extracted from the context of the entries, e.g. DefaultContextExtractors,
+	// the entries logged with entry.WithContext(ctx) or FromContext(ctx) will have the fields
+	ContextExtractors []ContextExtractor
+	// ContextSettings enables the context extractors if not nil, with `extractors` the names of the extractors,
+	// default DefaultContextExtractors; they are used after ContextExtractors
+	ContextSettings map[string]string
 }
 
+// NewLogger creates a logger with the level, writer, format and all enabled hooks,
+// without writer, the output is discarded and the logger relies on the hooks entirely
 func (c LogConfig) NewLogger() (*log.Logger, error) {
+	return c.newLogger(nil)
+}
+
+// newLogger creates the logger, the hooks are got from the shared hooks if not nil
+func (c LogConfig) newLogger(shared *sharedHooks) (*log.Logger, error) {
 	var logger = log.New()
 
 	level, err := log.ParseLevel(strings.ToLower(c.Level))
@@ -94,24 +138,17 @@ func (c LogConfig) NewLogger() (*log.Logger, error) {
 	}
 	logger.SetLevel(level)
 
-	// logger.SetOutput(c.getWriter())
-	logger.SetOutput(ioutil.Discard)
-	// TODO: move the setFormat another place, default without any format
-	// TODO: default NullFormatter
-	logger.SetFormatter(c.getDefaultFormatter())
-	// logger.SetFormatter(c.getFormatter())
+	c.setOutput(logger)
 
-	hooks, err := c.initHooks()
+	hooks, err := c.initSharedHooks(shared)
 	if err != nil {
 		log.WithError(err).Error("initHooks fail")
 	}
-	for _, hook := range hooks {
-		logger.AddHook(hook)
-	}
+	addHooks(logger, hooks)
 	return logger, nil
 }
 
-// Apply configures logger and all enabled hooks
+// Apply configures logger and all enabled hooks, without writer, the output is discarded, same as NewLogger
 func (c LogConfig) ApplyAsStdLogger() error {
 	level, err := log.ParseLevel(strings.ToLower(c.Level))
 	if nil != err {
@@ -119,24 +156,46 @@ func (c LogConfig) ApplyAsStdLogger() error {
 	}
 	log.SetLevel(level)
 
-	log.SetOutput(c.getWriter())
-	log.SetFormatter(c.getFormatter())
+	c.setOutput(log.StandardLogger())
 
 	hooks, err := c.initHooks()
 	if err != nil {
 		return err
 	}
-	for _, hook := range hooks {
-		log.AddHook(hook)
-	}
+	addHooks(log.StandardLogger(), hooks)
 	return nil
 }
 
+// setOutput sets the writer and formatter, the entries written to discard do not need to be formatted
+func (c LogConfig) setOutput(logger *log.Logger) {
+	if c.Writer == "" {
+		c.Writer = Discard
+	}
+	logger.SetOutput(c.getWriter())
+	if c.Writer == Discard {
+		logger.SetFormatter(c.getDefaultFormatter())
+	} else {
+		logger.SetFormatter(c.getFormatter())
+	}
+}
+
+// addHooks adds the hooks to logger, the formatter is wrapped if the sampling is enabled,
+// so the suppressed entries are not written either
+func addHooks(logger *log.Logger, hooks []log.Hook) {
+	for _, h := range hooks {
+		if sampling, ok := h.(*hook.SamplingLogHook); ok {
+			logger.SetFormatter(sampling.WrapFormatter(logger.Formatter))
+		}
+		logger.AddHook(h)
+	}
+}
+
 func (c LogConfig) getWriter() io.Writer {
 	switch c.Writer {
 	case StdOut:
 		return os.Stdout
-	case Discard:
+	case Discard, "":
+		// without writer, relies on the hooks entirely
 		return ioutil.Discard
 	case StdErr:
 		fallthrough
@@ -148,10 +207,42 @@ func (c LogConfig) getWriter() io.Writer {
 func (c LogConfig) getFormatter() log.Formatter {
 	switch c.Format {
 	case JSON:
+		limits, err := formatter.ParseLimits(c.FormatSettings)
+		if err != nil {
+			log.WithError(err).Error("invalid formatSettings, the limits are ignored")
+		}
 		// return &log.JSONFormatter{}
-		return &formatter.JSONFormatter{}
+		return &formatter.JSONFormatter{
+			SortKeys:          c.FormatSettings["sort_keys"] == "true",
+			PrettyPrint:       c.FormatSettings["pretty_print"] == "true",
+			RichErrors:        c.FormatSettings["rich_errors"] == "true",
+			ExpandDottedKeys:  c.FormatSettings["expand_keys"] == "true",
+			FlattenNestedKeys: c.FormatSettings["flatten_keys"] == "true",
+			Limits:            limits,
+		}
 	case Null:
 		return &formatter.NullFormatter{}
+	case Console:
+		return &formatter.ConsoleFormatter{}
+	case Logfmt:
+		return &formatter.LogfmtFormatter{FieldMap: formatter.ParseFieldMap(c.FormatSettings)}
+	case Logstash:
+		return &formatter.LogstashFormatter{
+			Version:         c.FormatSettings["version"],
+			Application:     c.FormatSettings["application"],
+			Hostname:        c.FormatSettings["hostname"],
+			DefaultHostname: c.FormatSettings["default_hostname"] == "true",
+		}
+	case ECS:
+		return &formatter.ECSFormatter{
+			ServiceName:     c.FormatSettings["service_name"],
+			Hostname:        c.FormatSettings["hostname"],
+			DefaultHostname: c.FormatSettings["default_hostname"] == "true",
+		}
+	case GELF:
+		return &formatter.GELFFormatter{Hostname: c.FormatSettings["hostname"]}
+	case Access:
+		return &formatter.AccessLogFormatter{Style: c.FormatSettings["style"], FieldNames: c.getAccessFieldNames()}
 	case Text:
 		fallthrough
 	default:
@@ -159,6 +250,32 @@ func (c LogConfig) getFormatter() log.Formatter {
 	}
 }
 
+// getAccessFieldNames returns the custom names of the access fields from the formatSettings `field_{name}`,
+// e.g. `field_remote_ip: client_ip`, nil if not set
+func (c LogConfig) getAccessFieldNames() map[string]string {
+	var names map[string]string
+	for k, v := range c.FormatSettings {
+		if strings.HasPrefix(k, accessFieldSettingPrefix) {
+			if names == nil {
+				names = map[string]string{}
+			}
+			names[strings.TrimPrefix(k, accessFieldSettingPrefix)] = v
+		}
+	}
+	return names
+}
+
+// getJSONFormatter returns the formatter of the json formats, e.g. json, logstash, ecs and gelf,
+// or the json formatter for the other formats, for the hooks sending json
+func (c LogConfig) getJSONFormatter() log.Formatter {
+	switch c.Format {
+	case JSON, Logstash, ECS, GELF:
+		return c.getFormatter()
+	default:
+		return &formatter.JSONFormatter{}
+	}
+}
+
 func (c LogConfig) getDefaultFormatter() log.Formatter {
 	return &formatter.NullFormatter{}
 }
@@ -177,20 +294,77 @@ func (e Errors) Error() string {
 }
 
 func (c LogConfig) initHooks() ([]log.Hook, error) {
+	return c.initSharedHooks(nil)
+}
+
+// initSharedHooks creates the hooks, the configured hooks are got from the shared hooks if not nil
+func (c LogConfig) initSharedHooks(shared *sharedHooks) ([]log.Hook, error) {
 	hooks := []log.Hook{}
 
 	errs := Errors{}
 	formatter := c.getFormatter()
 
+	// the sampling should be the first hook, then the context fields, then the redaction
+	var sampling *hook.SamplingLogHook
+	if c.SamplingSettings != nil {
+		lh, err := hook.SamplingLogHookBuilder{}.New("sampling", c.SamplingSettings)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "init sampling fail"))
+		} else {
+			sampling = lh.(*hook.SamplingLogHook)
+		}
+	}
+
+	extractors := c.ContextExtractors
+	if c.ContextSettings != nil {
+		settingExtractors, err := parseContextExtractors(c.ContextSettings)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "init context extractors fail"))
+		}
+		extractors = append(append([]ContextExtractor{}, extractors...), settingExtractors...)
+	}
+	if len(extractors) != 0 {
+		hooks = append(hooks, &contextHook{extractors: extractors})
+	}
+
+	if c.RedactSettings != nil {
+		lh, err := hook.RedactLogHookBuilder{}.New("redact", c.RedactSettings)
+		if err != nil {
+			errs = append(errs, errors.Wrap(err, "init redaction fail"))
+		} else {
+			hooks = append(hooks, lh)
+		}
+	}
+
 	for _, h := range c.Hooks {
 		var loghook hook.LogHookBuilder
 		switch h.Type {
 		case HookFile:
-			loghook = hook.FileLogHookBuilder{Formatter: formatter}
+			loghook = hook.FileLogHookBuilder{Formatter: formatter, Writers: shared.fileWriters()}
 		case HookSentry:
 			loghook = hook.SentryLogHookBuilder{}
 		case HookRedis:
 			loghook = hook.RedisLogHookBuilder{}
+		case HookSyslog:
+			loghook = hook.SyslogLogHookBuilder{}
+		case HookNet:
+			loghook = hook.NetLogHookBuilder{Formatter: formatter}
+		case HookHTTP:
+			loghook = hook.HTTPLogHookBuilder{Formatter: c.getJSONFormatter()}
+		case HookFluent:
+			loghook = hook.FluentLogHookBuilder{}
+		case HookKafka:
+			loghook = hook.KafkaLogHookBuilder{Formatter: c.getJSONFormatter()}
+		case HookOTLP:
+			loghook = hook.OTLPLogHookBuilder{}
+		case HookElasticsearch:
+			loghook = hook.ElasticsearchLogHookBuilder{}
+		case HookLoki:
+			loghook = hook.LokiLogHookBuilder{}
+		case HookConsole:
+			loghook = hook.ConsoleLogHookBuilder{}
+		case HookGELF:
+			loghook = hook.GELFLogHookBuilder{}
 		default:
 			loghook = nil
 		}
@@ -200,7 +374,14 @@ func (c LogConfig) initHooks() ([]log.Hook, error) {
 			return nil, ErrUnknownLogHookFormat
 		}
 
-		lh, err := loghook.New(h.Type, h.Settings)
+		lh, err := shared.getOrNew(c.hookKey(h), func() (log.Hook, error) {
+			lh, err := loghook.New(h.Type, h.Settings)
+			if err != nil {
+				return nil, err
+			}
+			// all the hooks support the dedup window
+			return hook.WithDedup(lh, h.Type, h.Settings)
+		})
 		if err != nil {
 			errs = append(errs, errors.Wrapf(err, "init log hook %s fail", h.Type))
 		} else {
@@ -208,6 +389,14 @@ func (c LogConfig) initHooks() ([]log.Hook, error) {
 		}
 	}
 
+	// the other hooks only fire the sampled entries
+	if sampling != nil {
+		for i := range hooks {
+			hooks[i] = sampling.Wrap(hooks[i])
+		}
+		hooks = append([]log.Hook{sampling}, hooks...)
+	}
+
 	if len(errs) != 0 {
 		return hooks, errors.New(errs.Error())
 	}
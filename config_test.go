@@ -1,9 +1,11 @@
 package logging
 
 import (
+	"bytes"
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/pkg/errors"
@@ -11,6 +13,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/wklken/logging-go/formatter"
+	"github.com/wklken/logging-go/hook"
 )
 
 func TestNewLogger(t *testing.T) {
@@ -45,6 +48,62 @@ func TestNewLogger(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestNewLoggerWriter(t *testing.T) {
+	var data = []struct {
+		writer            LogWriter
+		expectedOut       io.Writer
+		expectedFormatter log.Formatter
+	}{
+		// without writer, relies on the hooks
+		{"", ioutil.Discard, &formatter.NullFormatter{}},
+		{Discard, ioutil.Discard, &formatter.NullFormatter{}},
+		{StdOut, os.Stdout, &formatter.JSONFormatter{}},
+		{StdErr, os.Stderr, &formatter.JSONFormatter{}},
+	}
+	for _, d := range data {
+		c := LogConfig{Level: "info", Format: JSON, Writer: d.writer}
+
+		logger, err := c.NewLogger()
+		assert.NoError(t, err)
+		assert.Equal(t, d.expectedOut, logger.Out, d.writer)
+		assert.IsType(t, d.expectedFormatter, logger.Formatter, d.writer)
+	}
+
+	// the std logger is the same
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFormatter(&log.TextFormatter{})
+
+	for _, d := range data {
+		c := LogConfig{Level: "info", Format: JSON, Writer: d.writer}
+		assert.NoError(t, c.ApplyAsStdLogger())
+		assert.Equal(t, d.expectedOut, log.StandardLogger().Out, d.writer)
+		assert.IsType(t, d.expectedFormatter, log.StandardLogger().Formatter, d.writer)
+	}
+}
+
+func TestNewLoggerWriterStderr(t *testing.T) {
+	stderr := os.Stderr
+	defer func() { os.Stderr = stderr }()
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stderr = w
+
+	// the output was discarded before, even with writer stderr
+	for _, writer := range []LogWriter{"", Discard, StdErr} {
+		logger, err := LogConfig{Level: "info", Format: JSON, Writer: writer}.NewLogger()
+		assert.NoError(t, err)
+		logger.WithField("writer", writer).Info("hello")
+	}
+	assert.NoError(t, w.Close())
+
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"writer":"stderr"`)
+}
+
 func TestLogConfigApplyGetWriter(t *testing.T) {
 	var data = []struct {
 		writer   LogWriter
@@ -53,6 +112,7 @@ func TestLogConfigApplyGetWriter(t *testing.T) {
 		{StdErr, os.Stderr},
 		{StdOut, os.Stdout},
 		{Discard, ioutil.Discard},
+		{"", ioutil.Discard},
 		{LogWriter("unknown"), os.Stderr},
 	}
 	for _, d := range data {
@@ -69,6 +129,12 @@ func TestLogConfigApplyGetFormatter(t *testing.T) {
 
 		{Text, &log.TextFormatter{}},
 		{JSON, &formatter.JSONFormatter{}},
+		{Console, &formatter.ConsoleFormatter{}},
+		{Logfmt, &formatter.LogfmtFormatter{}},
+		{Logstash, &formatter.LogstashFormatter{}},
+		{ECS, &formatter.ECSFormatter{}},
+		{GELF, &formatter.GELFFormatter{}},
+		{Access, &formatter.AccessLogFormatter{}},
 		{LogFormat("unknown"), &log.TextFormatter{}},
 	}
 	for _, d := range data {
@@ -77,6 +143,52 @@ func TestLogConfigApplyGetFormatter(t *testing.T) {
 	}
 }
 
+func TestLogConfigGetFormatterSettings(t *testing.T) {
+	c := LogConfig{Format: JSON, FormatSettings: map[string]string{"sort_keys": "true", "pretty_print": "true", "rich_errors": "true",
+		"expand_keys": "true", "flatten_keys": "true", "max_field_length": "1024", "max_entry_size": "65536"}}
+	assert.Equal(t, &formatter.JSONFormatter{SortKeys: true, PrettyPrint: true, RichErrors: true,
+		ExpandDottedKeys: true, FlattenNestedKeys: true,
+		Limits: formatter.Limits{MaxFieldLength: 1024, MaxEntrySize: 65536}}, c.getFormatter())
+
+	// the invalid limits are ignored
+	c = LogConfig{Format: JSON, FormatSettings: map[string]string{"max_fields": "a"}}
+	assert.Equal(t, &formatter.JSONFormatter{}, c.getFormatter())
+
+	c = LogConfig{Format: Logstash, FormatSettings: map[string]string{"version": "v0", "application": "app1"}}
+	assert.Equal(t, &formatter.LogstashFormatter{Version: formatter.LogstashV0, Application: "app1"}, c.getFormatter())
+
+	c = LogConfig{Format: ECS, FormatSettings: map[string]string{"service_name": "api", "hostname": "localhost"}}
+	assert.Equal(t, &formatter.ECSFormatter{ServiceName: "api", Hostname: "localhost"}, c.getFormatter())
+
+	c = LogConfig{Format: Logfmt, FormatSettings: map[string]string{"field_map_msg": "message", "field_map_time": "@timestamp"}}
+	assert.Equal(t, &formatter.LogfmtFormatter{FieldMap: formatter.FieldMap{
+		formatter.FieldKeyMsg: "message", formatter.FieldKeyTime: "@timestamp"}}, c.getFormatter())
+
+	c = LogConfig{Format: Access, FormatSettings: map[string]string{"style": "common", "field_remote_ip": "client_ip"}}
+	assert.Equal(t, &formatter.AccessLogFormatter{Style: formatter.AccessLogCommon,
+		FieldNames: map[string]string{formatter.AccessFieldRemoteIP: "client_ip"}}, c.getFormatter())
+}
+
+func TestLogConfigGetJSONFormatter(t *testing.T) {
+	var data = []struct {
+		format   LogFormat
+		expected log.Formatter
+	}{
+		{"", &formatter.JSONFormatter{}},
+		{Text, &formatter.JSONFormatter{}},
+		{Logfmt, &formatter.JSONFormatter{}},
+		{JSON, &formatter.JSONFormatter{}},
+		{Logstash, &formatter.LogstashFormatter{}},
+		{ECS, &formatter.ECSFormatter{}},
+		{GELF, &formatter.GELFFormatter{}},
+	}
+
+	for _, d := range data {
+		c := LogConfig{Format: d.format}
+		assert.IsType(t, d.expected, c.getJSONFormatter())
+	}
+}
+
 func TestLogConfigApply(t *testing.T) {
 	c := LogConfig{Level: "warning"}
 
@@ -156,6 +268,90 @@ func TestInitHooks(t *testing.T) {
 	hooks, err := l.initHooks()
 	assert.NoError(t, err)
 	assert.Len(t, hooks, 1)
+
+	// syslog over udp, will init success
+	l.Hooks = []LogHook{
+		{Type: "syslog", Settings: map[string]string{"host": "127.0.0.1", "async_enable": "false"}},
+	}
+
+	hooks, err = l.initHooks()
+	assert.NoError(t, err)
+	assert.Len(t, hooks, 1)
+
+	// console, will init success
+	l.Hooks = []LogHook{
+		{Type: "console", Settings: map[string]string{"writer": "stdout", "level": "warning"}},
+	}
+
+	hooks, err = l.initHooks()
+	assert.NoError(t, err)
+	assert.Len(t, hooks, 1)
+	assert.Len(t, hooks[0].Levels(), 4)
+
+	// dedup window
+	l.Hooks = []LogHook{
+		{Type: "console", Settings: map[string]string{"writer": "stdout", "dedup_window": "1m"}},
+	}
+	hooks, err = l.initHooks()
+	assert.NoError(t, err)
+	assert.IsType(t, &hook.DedupLogHook{}, hooks[0])
+
+	l.Hooks = []LogHook{
+		{Type: "console", Settings: map[string]string{"writer": "stdout", "dedup_window": "a"}},
+	}
+	_, err = l.initHooks()
+	assert.Error(t, err)
+
+	l.Hooks = []LogHook{
+		{Type: "console", Settings: map[string]string{"writer": "stdout", "level": "warning"}},
+	}
+
+	// redaction, will be the first hook
+	l.RedactSettings = map[string]string{}
+	hooks, err = l.initHooks()
+	assert.NoError(t, err)
+	assert.Len(t, hooks, 2)
+	assert.IsType(t, &hook.RedactLogHook{}, hooks[0])
+
+	l.RedactSettings = map[string]string{"mode": "drop"}
+	hooks, err = l.initHooks()
+	assert.Error(t, err)
+	assert.Len(t, hooks, 1)
+
+	// sampling, will be the first hook, the others are wrapped
+	l.RedactSettings = map[string]string{}
+	l.SamplingSettings = map[string]string{"first": "10", "summary_interval": "0"}
+	hooks, err = l.initHooks()
+	assert.NoError(t, err)
+	assert.Len(t, hooks, 3)
+	assert.IsType(t, &hook.SamplingLogHook{}, hooks[0])
+	_, ok := hooks[1].(*hook.RedactLogHook)
+	assert.False(t, ok)
+	assert.Len(t, hooks[2].Levels(), 4)
+
+	l.SamplingSettings = map[string]string{"first": "a"}
+	_, err = l.initHooks()
+	assert.Error(t, err)
+}
+
+func TestNewLoggerSampling(t *testing.T) {
+	l := LogConfig{
+		Level:            "info",
+		Format:           Text,
+		Writer:           StdOut,
+		SamplingSettings: map[string]string{"first": "1", "summary_interval": "0"},
+	}
+	logger, err := l.NewLogger()
+	assert.NoError(t, err)
+	assert.Len(t, logger.Hooks[log.InfoLevel], 1)
+
+	// the suppressed entries are not written
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	for i := 0; i < 3; i++ {
+		logger.Info("hello")
+	}
+	assert.Equal(t, 1, strings.Count(buf.String(), "hello"))
 }
 
 func TestErrorArray(t *testing.T) {
@@ -0,0 +1,172 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the fields added by the default context extractors
+const (
+	FieldKeyRequestID = "request_id"
+	FieldKeyTraceID   = "trace_id"
+	FieldKeySpanID    = "span_id"
+	FieldKeyUserID    = "user_id"
+)
+
+type contextKey int
+
+const (
+	entryContextKey contextKey = iota
+	requestIDContextKey
+	traceIDContextKey
+	spanIDContextKey
+	userIDContextKey
+)
+
+// ContextExtractor returns the fields extracted from the context
+type ContextExtractor func(ctx context.Context) log.Fields
+
+// DefaultContextExtractors extracts the request_id, trace_id, span_id and user_id set by ContextWithXXX,
+// then the trace_id and span_id of the opentelemetry span, the ones set by ContextWithXXX take precedence
+var DefaultContextExtractors = []ContextExtractor{
+	ContextValueExtractor(FieldKeyRequestID, requestIDContextKey),
+	ContextValueExtractor(FieldKeyTraceID, traceIDContextKey),
+	ContextValueExtractor(FieldKeySpanID, spanIDContextKey),
+	ContextValueExtractor(FieldKeyUserID, userIDContextKey),
+	OTelContextExtractor,
+}
+
+// the names of the extractors in ContextSettings `extractors`
+var contextExtractorNames = map[string]ContextExtractor{
+	FieldKeyRequestID: DefaultContextExtractors[0],
+	FieldKeyTraceID:   DefaultContextExtractors[1],
+	FieldKeySpanID:    DefaultContextExtractors[2],
+	FieldKeyUserID:    DefaultContextExtractors[3],
+	"otel":            OTelContextExtractor,
+}
+
+// OTelContextExtractor extracts the trace_id and span_id of the opentelemetry span in the context,
+// e.g. started by the tracer or propagated by the otel middlewares
+func OTelContextExtractor(ctx context.Context) log.Fields {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	return log.Fields{FieldKeyTraceID: sc.TraceID().String(), FieldKeySpanID: sc.SpanID().String()}
+}
+
+// parseContextExtractors parses the settings `extractors`, the names joined by `,`,
+// `request_id`, `trace_id`, `span_id`, `user_id` and `otel`, default is DefaultContextExtractors
+func parseContextExtractors(settings map[string]string) ([]ContextExtractor, error) {
+	names := strings.TrimSpace(settings["extractors"])
+	if names == "" {
+		return DefaultContextExtractors, nil
+	}
+
+	var extractors []ContextExtractor
+	for _, name := range strings.Split(names, ",") {
+		extractor, ok := contextExtractorNames[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported context extractor %s, should be request_id, trace_id, span_id, user_id or otel", name)
+		}
+		extractors = append(extractors, extractor)
+	}
+	return extractors, nil
+}
+
+// ContextValueExtractor returns the extractor of ctx.Value(key) as the field, e.g. the key of other packages
+func ContextValueExtractor(field string, key interface{}) ContextExtractor {
+	return func(ctx context.Context) log.Fields {
+		if v := ctx.Value(key); v != nil && v != "" {
+			return log.Fields{field: v}
+		}
+		return nil
+	}
+}
+
+// ContextWithRequestID returns a copy of ctx with the request id
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, requestID)
+}
+
+// RequestIDFromContext returns the request id set by ContextWithRequestID, empty if absent
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDContextKey).(string)
+	return requestID
+}
+
+// ContextWithTraceID returns a copy of ctx with the trace id
+func ContextWithTraceID(ctx context.Context, traceID string) context.Context {
+	return context.WithValue(ctx, traceIDContextKey, traceID)
+}
+
+// ContextWithSpanID returns a copy of ctx with the span id
+func ContextWithSpanID(ctx context.Context, spanID string) context.Context {
+	return context.WithValue(ctx, spanIDContextKey, spanID)
+}
+
+// ContextWithUserID returns a copy of ctx with the user id
+func ContextWithUserID(ctx context.Context, userID interface{}) context.Context {
+	return context.WithValue(ctx, userIDContextKey, userID)
+}
+
+// WithContext returns a copy of ctx with the entry, which can be got by FromContext
+func WithContext(ctx context.Context, entry *log.Entry) context.Context {
+	return context.WithValue(ctx, entryContextKey, entry)
+}
+
+// FromContext returns the entry in ctx, or the entry of the standard logger, with the context set,
+// so the fields are extracted by the context extractors when logging
+func FromContext(ctx context.Context) *log.Entry {
+	entry, ok := ctx.Value(entryContextKey).(*log.Entry)
+	if !ok || entry == nil {
+		entry = log.NewEntry(log.StandardLogger())
+	}
+	return entry.WithContext(ctx)
+}
+
+// contextHook adds the fields extracted from entry.Context, the fields of the entry are not overwritten
+type contextHook struct {
+	extractors []ContextExtractor
+}
+
+// Fire is called when a log event is fired.
+func (h *contextHook) Fire(entry *log.Entry) error {
+	if entry.Context == nil {
+		return nil
+	}
+
+	var data log.Fields
+	for _, extractor := range h.extractors {
+		for k, v := range extractor(entry.Context) {
+			current := entry.Data
+			if data != nil {
+				current = data
+			}
+			if _, ok := current[k]; ok {
+				continue
+			}
+			// the data may be shared with the entry held by the caller, so copy it
+			if data == nil {
+				data = make(log.Fields, len(entry.Data)+4)
+				for key, value := range entry.Data {
+					data[key] = value
+				}
+			}
+			data[k] = v
+		}
+	}
+	if data != nil {
+		entry.Data = data
+	}
+	return nil
+}
+
+// Levels returns the available logging levels.
+func (h *contextHook) Levels() []log.Level {
+	return log.AllLevels
+}
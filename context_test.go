@@ -0,0 +1,176 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/wklken/logging-go/formatter"
+)
+
+type testContextRecorder struct {
+	entries []*log.Entry
+}
+
+func (r *testContextRecorder) Fire(entry *log.Entry) error {
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *testContextRecorder) Levels() []log.Level {
+	return log.AllLevels
+}
+
+type testOtherContextKey struct{}
+
+func TestContextExtractors(t *testing.T) {
+	ctx := context.Background()
+	for _, extractor := range DefaultContextExtractors {
+		assert.Empty(t, extractor(ctx))
+	}
+
+	assert.Equal(t, "", RequestIDFromContext(ctx))
+	ctx = ContextWithRequestID(ctx, "req1")
+	assert.Equal(t, "req1", RequestIDFromContext(ctx))
+	ctx = ContextWithTraceID(ctx, "trace1")
+	ctx = ContextWithSpanID(ctx, "span1")
+	ctx = ContextWithUserID(ctx, 42)
+
+	fields := log.Fields{}
+	for _, extractor := range DefaultContextExtractors {
+		for k, v := range extractor(ctx) {
+			fields[k] = v
+		}
+	}
+	assert.Equal(t, log.Fields{"request_id": "req1", "trace_id": "trace1", "span_id": "span1", "user_id": 42}, fields)
+
+	// the empty value is ignored
+	assert.Empty(t, DefaultContextExtractors[0](ContextWithRequestID(context.Background(), "")))
+
+	extractor := ContextValueExtractor("tenant", testOtherContextKey{})
+	assert.Empty(t, extractor(ctx))
+	assert.Equal(t, log.Fields{"tenant": "t1"}, extractor(context.WithValue(ctx, testOtherContextKey{}, "t1")))
+}
+
+func newTestSpanContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10},
+		SpanID:  trace.SpanID{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+	}))
+}
+
+func TestOTelContextExtractor(t *testing.T) {
+	assert.Empty(t, OTelContextExtractor(context.Background()))
+
+	ctx := newTestSpanContext(context.Background())
+	assert.Equal(t, log.Fields{"trace_id": "0102030405060708090a0b0c0d0e0f10", "span_id": "0102030405060708"},
+		OTelContextExtractor(ctx))
+
+	// the ones set by ContextWithXXX take precedence
+	logger := log.New()
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&formatter.JSONFormatter{})
+	logger.AddHook(&contextHook{extractors: DefaultContextExtractors})
+	logger.WithContext(ContextWithTraceID(ctx, "trace1")).Info("hello")
+
+	data := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, "trace1", data["trace_id"])
+	assert.Equal(t, "0102030405060708", data["span_id"])
+}
+
+func TestParseContextExtractors(t *testing.T) {
+	extractors, err := parseContextExtractors(map[string]string{})
+	assert.NoError(t, err)
+	assert.Len(t, extractors, len(DefaultContextExtractors))
+
+	extractors, err = parseContextExtractors(map[string]string{"extractors": "request_id, otel"})
+	assert.NoError(t, err)
+	assert.Len(t, extractors, 2)
+
+	_, err = parseContextExtractors(map[string]string{"extractors": "request_id,tenant"})
+	assert.Error(t, err)
+}
+
+func TestFromContext(t *testing.T) {
+	ctx := context.Background()
+
+	// the standard logger
+	entry := FromContext(ctx)
+	assert.Equal(t, log.StandardLogger(), entry.Logger)
+	assert.Equal(t, ctx, entry.Context)
+
+	logger := log.New()
+	ctx = WithContext(ctx, logger.WithField("a", 1))
+	entry = FromContext(ctx)
+	assert.Equal(t, logger, entry.Logger)
+	assert.Equal(t, ctx, entry.Context)
+	assert.Equal(t, log.Fields{"a": 1}, entry.Data)
+}
+
+func TestContextHook(t *testing.T) {
+	var buf bytes.Buffer
+	recorder := &testContextRecorder{}
+	logger := log.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&formatter.JSONFormatter{})
+	logger.AddHook(&contextHook{extractors: DefaultContextExtractors})
+	logger.AddHook(recorder)
+
+	ctx := ContextWithTraceID(ContextWithRequestID(context.Background(), "req1"), "trace1")
+	entry := logger.WithField("request_id", "mine")
+	entry.WithContext(ctx).Info("hello")
+
+	data := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &data))
+	assert.Equal(t, "trace1", data["trace_id"])
+	// the fields of the entry are not overwritten
+	assert.Equal(t, "mine", data["request_id"])
+	assert.NotContains(t, data, "span_id")
+
+	// the hooks after it have the fields too
+	if assert.Len(t, recorder.entries, 1) {
+		assert.Equal(t, "trace1", recorder.entries[0].Data["trace_id"])
+	}
+	// the entry held by the caller is not modified
+	assert.Equal(t, log.Fields{"request_id": "mine"}, entry.Data)
+
+	// without context
+	buf.Reset()
+	logger.Info("world")
+	assert.NotContains(t, buf.String(), "trace_id")
+
+	// via FromContext
+	buf.Reset()
+	FromContext(WithContext(ctx, log.NewEntry(logger))).Info("hi")
+	assert.Contains(t, buf.String(), `"request_id":"req1"`)
+}
+
+func TestNewLoggerContextExtractors(t *testing.T) {
+	l := LogConfig{Level: "info", Format: JSON, Writer: StdOut, ContextExtractors: DefaultContextExtractors}
+	logger, err := l.NewLogger()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.WithContext(ContextWithUserID(context.Background(), "tom")).Info("hello")
+	assert.Contains(t, buf.String(), `"user_id":"tom"`)
+}
+
+func TestNewLoggerContextSettings(t *testing.T) {
+	l := LogConfig{Level: "info", Format: JSON, Writer: StdOut, ContextSettings: map[string]string{"extractors": "otel"}}
+	logger, err := l.NewLogger()
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.WithContext(newTestSpanContext(ContextWithUserID(context.Background(), "tom"))).Info("hello")
+	assert.Contains(t, buf.String(), `"trace_id":"0102030405060708090a0b0c0d0e0f10"`)
+	assert.NotContains(t, buf.String(), `"user_id"`)
+}
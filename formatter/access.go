@@ -0,0 +1,128 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	AccessLogCombined = "combined"
+	AccessLogCommon   = "common"
+	AccessLogJSON     = "json"
+
+	apacheTimestampFormat = "02/Jan/2006:15:04:05 -0700"
+)
+
+// the default field names of the access log entries
+const (
+	AccessFieldMethod    = "method"
+	AccessFieldPath      = "path"
+	AccessFieldQuery     = "query"
+	AccessFieldProto     = "proto"
+	AccessFieldStatus    = "status"
+	AccessFieldBytes     = "bytes"
+	AccessFieldDuration  = "duration_ms"
+	AccessFieldRemoteIP  = "remote_ip"
+	AccessFieldUser      = "user"
+	AccessFieldUserAgent = "user_agent"
+	AccessFieldReferer   = "referer"
+	AccessFieldRequestID = "request_id"
+)
+
+// AccessLogFormatter formats the access log entries, e.g. logged by middleware.NewAccessLogMiddleware:
+//
+//	combined: 127.0.0.1 - tom [01/May/2020:10:00:00 +0000] "GET /api?a=1 HTTP/1.1" 200 1234 "http://a.com/" "curl/7.64.1"
+//	common:   127.0.0.1 - tom [01/May/2020:10:00:00 +0000] "GET /api?a=1 HTTP/1.1" 200 1234
+//	json:     {"time": "...", "level": "info", "msg": "...", "bytes": 1234, "method": "GET", ...}
+//
+// the entries without the method field are formatted as logfmt in combined and common style
+type AccessLogFormatter struct {
+	// Style is combined(default), common or json
+	Style string
+
+	// FieldNames is the custom names of the access fields, e.g. {"remote_ip": "client_ip"},
+	// should be the same as the middleware
+	FieldNames map[string]string
+
+	// JSON is the formatter of the json style, default is the JSONFormatter with the sorted keys
+	JSON *JSONFormatter
+}
+
+// the formatters are stateless, shared by all the access log formatters
+var (
+	defaultAccessJSONFormatter   = &JSONFormatter{SortKeys: true}
+	defaultAccessLogfmtFormatter = &LogfmtFormatter{}
+)
+
+// Format renders a single log entry
+func (f *AccessLogFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if f.Style == AccessLogJSON {
+		if f.JSON != nil {
+			return f.JSON.Format(entry)
+		}
+		return defaultAccessJSONFormatter.Format(entry)
+	}
+
+	method, ok := entry.Data[f.field(AccessFieldMethod)]
+	if !ok {
+		return defaultAccessLogfmtFormatter.Format(entry)
+	}
+
+	// ignore the entry.Buffer, same as JSONFormatter, the entry may be formatted by the async hooks
+	// after logrus has put the pooled buffer back
+	b := &bytes.Buffer{}
+
+	uri := f.value(entry, AccessFieldPath)
+	if query := f.value(entry, AccessFieldQuery); query != "-" {
+		uri += "?" + query
+	}
+
+	b.WriteString(f.value(entry, AccessFieldRemoteIP))
+	b.WriteString(" - ")
+	b.WriteString(f.value(entry, AccessFieldUser))
+	b.WriteString(" [")
+	b.WriteString(entry.Time.Format(apacheTimestampFormat))
+	b.WriteString("] \"")
+	fmt.Fprintf(b, "%v %s %s", method, uri, f.value(entry, AccessFieldProto))
+	b.WriteString("\" ")
+	b.WriteString(f.value(entry, AccessFieldStatus))
+	b.WriteByte(' ')
+
+	// %b, `-` for no bytes
+	size := f.value(entry, AccessFieldBytes)
+	if size == "0" {
+		size = "-"
+	}
+	b.WriteString(size)
+
+	if f.Style != AccessLogCommon {
+		b.WriteByte(' ')
+		b.WriteString(strconv.Quote(f.value(entry, AccessFieldReferer)))
+		b.WriteByte(' ')
+		b.WriteString(strconv.Quote(f.value(entry, AccessFieldUserAgent)))
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+func (f *AccessLogFormatter) field(name string) string {
+	if custom, ok := f.FieldNames[name]; ok {
+		return custom
+	}
+	return name
+}
+
+// value returns the field as string, `-` if absent or empty, same as apache
+func (f *AccessLogFormatter) value(entry *logrus.Entry, name string) string {
+	v, ok := entry.Data[f.field(name)]
+	if !ok {
+		return "-"
+	}
+	if s := fmt.Sprint(v); s != "" {
+		return s
+	}
+	return "-"
+}
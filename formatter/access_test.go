@@ -0,0 +1,110 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAccessTestEntry(fields logrus.Fields) *logrus.Entry {
+	entry := logrus.WithFields(fields)
+	entry.Time = time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	entry.Level = logrus.InfoLevel
+	entry.Message = "GET /api 200"
+	return entry
+}
+
+func TestAccessLogFormatter(t *testing.T) {
+	fields := logrus.Fields{
+		AccessFieldMethod:    "GET",
+		AccessFieldPath:      "/api",
+		AccessFieldQuery:     "a=1",
+		AccessFieldProto:     "HTTP/1.1",
+		AccessFieldStatus:    200,
+		AccessFieldBytes:     1234,
+		AccessFieldDuration:  1.5,
+		AccessFieldRemoteIP:  "127.0.0.1",
+		AccessFieldUser:      "tom",
+		AccessFieldUserAgent: "curl/7.64.1",
+		AccessFieldReferer:   "http://a.com/",
+		AccessFieldRequestID: "abc",
+	}
+
+	tests := []struct {
+		name      string
+		formatter *AccessLogFormatter
+		fields    logrus.Fields
+		want      string
+	}{
+		{
+			name:      "combined",
+			formatter: &AccessLogFormatter{},
+			fields:    fields,
+			want:      `127.0.0.1 - tom [01/May/2020:10:00:00 +0000] "GET /api?a=1 HTTP/1.1" 200 1234 "http://a.com/" "curl/7.64.1"` + "\n",
+		},
+		{
+			name:      "common",
+			formatter: &AccessLogFormatter{Style: AccessLogCommon},
+			fields:    fields,
+			want:      `127.0.0.1 - tom [01/May/2020:10:00:00 +0000] "GET /api?a=1 HTTP/1.1" 200 1234` + "\n",
+		},
+		{
+			name:      "absent fields",
+			formatter: &AccessLogFormatter{},
+			fields:    logrus.Fields{AccessFieldMethod: "POST", AccessFieldPath: "/", AccessFieldStatus: 204, AccessFieldBytes: 0},
+			want:      `- - - [01/May/2020:10:00:00 +0000] "POST / -" 204 - "-" "-"` + "\n",
+		},
+		{
+			name:      "field names",
+			formatter: &AccessLogFormatter{Style: AccessLogCommon, FieldNames: map[string]string{AccessFieldRemoteIP: "client_ip"}},
+			fields:    logrus.Fields{AccessFieldMethod: "GET", AccessFieldPath: "/", "client_ip": "10.0.0.1", AccessFieldStatus: 200},
+			want:      `10.0.0.1 - - [01/May/2020:10:00:00 +0000] "GET / -" 200 -` + "\n",
+		},
+		{
+			name:      "not access log",
+			formatter: &AccessLogFormatter{},
+			fields:    logrus.Fields{"a": 1},
+			want:      `time=2020-05-01T10:00:00Z level=info msg="GET /api 200" a=1` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.formatter.Format(newAccessTestEntry(tt.fields))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(b))
+		})
+	}
+}
+
+func TestAccessLogFormatterJSON(t *testing.T) {
+	b, err := (&AccessLogFormatter{Style: AccessLogJSON}).Format(newAccessTestEntry(logrus.Fields{
+		AccessFieldMethod: "GET", AccessFieldStatus: 200,
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"time":"2020-05-01T10:00:00Z","level":"info","msg":"GET /api 200","method":"GET","status":200}`+"\n", string(b))
+
+	data := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(b, &data))
+
+	// the custom json formatter is used
+	b, err = (&AccessLogFormatter{Style: AccessLogJSON, JSON: &JSONFormatter{DisableTimestamp: true, SortKeys: true}}).Format(
+		newAccessTestEntry(logrus.Fields{AccessFieldMethod: "GET", AccessFieldStatus: 200}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"GET /api 200","method":"GET","status":200}`+"\n", string(b))
+}
+
+func TestAccessLogFormatterIgnoreBuffer(t *testing.T) {
+	for _, style := range []string{AccessLogCombined, AccessLogJSON} {
+		entry := newAccessTestEntry(logrus.Fields{AccessFieldMethod: "GET", AccessFieldStatus: 200})
+		entry.Buffer = &bytes.Buffer{}
+		b, err := (&AccessLogFormatter{Style: style}).Format(entry)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, b)
+		assert.Equal(t, 0, entry.Buffer.Len(), style)
+	}
+}
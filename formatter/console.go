@@ -0,0 +1,168 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultConsoleTimestampFormat = "15:04:05.000"
+	defaultConsoleMessageWidth    = 40
+
+	colorReset = "\x1b[0m"
+	colorDim   = "\x1b[2m"
+	colorRed   = "\x1b[31m"
+	colorCyan  = "\x1b[36m"
+)
+
+// the badges are `bold;fg;bg`
+var consoleLevelBadges = map[logrus.Level]string{
+	logrus.TraceLevel: "\x1b[1;97;100m",
+	logrus.DebugLevel: "\x1b[1;97;100m",
+	logrus.InfoLevel:  "\x1b[1;30;42m",
+	logrus.WarnLevel:  "\x1b[1;30;43m",
+	logrus.ErrorLevel: "\x1b[1;97;41m",
+	logrus.FatalLevel: "\x1b[1;97;45m",
+	logrus.PanicLevel: "\x1b[1;97;45m",
+}
+
+// ConsoleFormatter formats logs into colored and aligned lines for local development, like:
+//
+//	10:00:00.000  INFO   main.go:42  hello                                   id=1 user="tom cat"
+//
+// the error with the stack(e.g. `github.com/pkg/errors`) will be rendered in the following lines.
+type ConsoleFormatter struct {
+	// ForceColors enables the colors even the output is not a terminal
+	ForceColors bool
+
+	// DisableColors disables the colors, by default the colors are enabled if the output is a terminal
+	DisableColors bool
+
+	// TimestampFormat sets the format of the timestamp, default is `15:04:05.000`
+	TimestampFormat string
+
+	// MessageWidth pads the message to the width, so the fields are aligned, default is 40
+	MessageWidth int
+
+	terminalInitOnce sync.Once
+	isTerminal       bool
+}
+
+// Format renders a single log entry
+func (f *ConsoleFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	f.terminalInitOnce.Do(func() {
+		if entry.Logger != nil {
+			f.isTerminal = IsTerminal(entry.Logger.Out)
+		}
+	})
+	colored := f.ForceColors || (f.isTerminal && !f.DisableColors)
+
+	// ignore the entry.Buffer, same as JSONFormatter, the entry may be formatted by the async hooks
+	// after logrus has put the pooled buffer back
+	b := &bytes.Buffer{}
+
+	timestampFormat := f.TimestampFormat
+	if timestampFormat == "" {
+		timestampFormat = defaultConsoleTimestampFormat
+	}
+	messageWidth := f.MessageWidth
+	if messageWidth == 0 {
+		messageWidth = defaultConsoleMessageWidth
+	}
+
+	f.writeColored(b, colored, colorDim, entry.Time.Format(timestampFormat))
+	b.WriteByte(' ')
+
+	level := strings.ToUpper(entry.Level.String())
+	if entry.Level == logrus.WarnLevel {
+		level = "WARN"
+	}
+	f.writeColored(b, colored, consoleLevelBadges[entry.Level], fmt.Sprintf(" %-5s ", level))
+
+	b.WriteByte(' ')
+
+	if entry.HasCaller() {
+		f.writeColored(b, colored, colorDim, fmt.Sprintf("%s:%d", filepath.Base(entry.Caller.File), entry.Caller.Line))
+		b.WriteString("  ")
+	}
+
+	b.WriteString(entry.Message)
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) > 0 && len(entry.Message) < messageWidth {
+		b.WriteString(strings.Repeat(" ", messageWidth-len(entry.Message)))
+	}
+
+	// the details of errors, rendered after the line
+	var details []string
+	for _, k := range keys {
+		b.WriteByte(' ')
+		f.writeColored(b, colored, colorCyan, k)
+		b.WriteByte('=')
+
+		v := entry.Data[k]
+		if err, ok := v.(error); ok {
+			msg := err.Error()
+			if colored {
+				b.WriteString(colorRed)
+			}
+			b.WriteString(quoteIfNeeded(msg))
+			if colored {
+				b.WriteString(colorReset)
+			}
+
+			if detail := fmt.Sprintf("%+v", err); detail != msg && strings.Contains(detail, "\n") {
+				details = append(details, k+": "+detail)
+			}
+			continue
+		}
+		b.WriteString(quoteIfNeeded(fmt.Sprint(v)))
+	}
+	b.WriteByte('\n')
+
+	for _, detail := range details {
+		for _, line := range strings.Split(strings.TrimRight(detail, "\n"), "\n") {
+			b.WriteString("    ")
+			f.writeColored(b, colored, colorDim, line)
+			b.WriteByte('\n')
+		}
+	}
+
+	return b.Bytes(), nil
+}
+
+func (f *ConsoleFormatter) writeColored(b *bytes.Buffer, colored bool, color, s string) {
+	if !colored {
+		b.WriteString(s)
+		return
+	}
+	b.WriteString(color)
+	b.WriteString(s)
+	b.WriteString(colorReset)
+}
+
+// IsTerminal returns true if the writer is a character device, e.g. a tty
+func IsTerminal(writer io.Writer) bool {
+	f, ok := writer.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,100 @@
+package formatter
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newConsoleTestEntry(level logrus.Level, msg string, fields logrus.Fields) *logrus.Entry {
+	logger := logrus.New()
+	logger.Out = &bytes.Buffer{}
+
+	entry := logrus.NewEntry(logger).WithFields(fields)
+	entry.Time = time.Date(2020, 5, 1, 10, 0, 0, 123000000, time.UTC)
+	entry.Level = level
+	entry.Message = msg
+	return entry
+}
+
+func TestConsoleFormatter(t *testing.T) {
+	f := &ConsoleFormatter{MessageWidth: 10}
+
+	var data = []struct {
+		level    logrus.Level
+		msg      string
+		fields   logrus.Fields
+		expected string
+	}{
+		{logrus.InfoLevel, "hello", logrus.Fields{}, "10:00:00.123  INFO   hello\n"},
+		{logrus.WarnLevel, "hello", logrus.Fields{"b": 2, "a": "x y"}, "10:00:00.123  WARN   hello      a=\"x y\" b=2\n"},
+		{logrus.ErrorLevel, "a long message", logrus.Fields{"error": errors.New("oops")}, "10:00:00.123  ERROR  a long message error=oops\n"},
+		{logrus.DebugLevel, "hello", logrus.Fields{"empty": "", "eq": "a=b"}, "10:00:00.123  DEBUG  hello      empty=\"\" eq=\"a=b\"\n"},
+	}
+	for _, d := range data {
+		b, err := f.Format(newConsoleTestEntry(d.level, d.msg, d.fields))
+		assert.NoError(t, err)
+		assert.Equal(t, d.expected, string(b))
+	}
+}
+
+func TestConsoleFormatterCaller(t *testing.T) {
+	f := &ConsoleFormatter{}
+
+	entry := newConsoleTestEntry(logrus.InfoLevel, "hello", logrus.Fields{})
+	entry.Logger.ReportCaller = true
+	entry.Caller = &runtime.Frame{File: "/src/app/main.go", Line: 42}
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "10:00:00.123  INFO   main.go:42  hello\n", string(b))
+}
+
+func TestConsoleFormatterErrorStack(t *testing.T) {
+	f := &ConsoleFormatter{}
+
+	entry := newConsoleTestEntry(logrus.ErrorLevel, "fail", logrus.Fields{"error": pkgerrors.New("oops")})
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	assert.True(t, len(lines) > 2)
+	assert.True(t, strings.HasSuffix(lines[0], "error=oops"))
+	assert.Equal(t, "    error: oops", lines[1])
+	assert.Contains(t, lines[2], "TestConsoleFormatterErrorStack")
+}
+
+func TestConsoleFormatterColors(t *testing.T) {
+	entry := newConsoleTestEntry(logrus.ErrorLevel, "fail", logrus.Fields{"error": errors.New("oops")})
+
+	// the output is not a terminal
+	b, err := (&ConsoleFormatter{}).Format(entry)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "\x1b[")
+
+	b, err = (&ConsoleFormatter{ForceColors: true}).Format(entry)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), consoleLevelBadges[logrus.ErrorLevel]+" ERROR "+colorReset)
+	assert.Contains(t, string(b), colorCyan+"error"+colorReset+"="+colorRed+"oops"+colorReset)
+}
+
+func TestConsoleFormatterIgnoreBuffer(t *testing.T) {
+	entry := newConsoleTestEntry(logrus.InfoLevel, "hello", logrus.Fields{"a": 1})
+	entry.Buffer = &bytes.Buffer{}
+	b, err := (&ConsoleFormatter{DisableColors: true}).Format(entry)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "hello")
+	assert.Equal(t, 0, entry.Buffer.Len())
+}
+
+func TestIsTerminal(t *testing.T) {
+	assert.False(t, IsTerminal(&bytes.Buffer{}))
+	assert.False(t, IsTerminal(nil))
+}
@@ -0,0 +1,82 @@
+package formatter
+
+import (
+	"fmt"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+const ecsVersion = "1.6.0"
+
+// ECSFormatter formats logs into the Elastic Common Schema json, like:
+//
+//	{"@timestamp": "...", "log.level": "error", "message": "...", "ecs.version": "1.6.0",
+//	 "service.name": "...", "host.hostname": "...", "error.message": "...", "error.type": "*errors.fundamental", ...}
+//
+// https://www.elastic.co/guide/en/ecs/current/ecs-field-reference.html
+type ECSFormatter struct {
+	// ServiceName is the value of `service.name`, omitted if empty
+	ServiceName string
+
+	// Hostname is the value of `host.hostname`, omitted if empty
+	Hostname string
+
+	// DefaultHostname uses the hostname of the machine if Hostname is empty
+	DefaultHostname bool
+}
+
+// Format renders a single log entry
+func (f *ECSFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	m := make(map[string]interface{}, len(entry.Data)+8)
+	m["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
+	m["log.level"] = entry.Level.String()
+	m["message"] = entry.Message
+	m["ecs.version"] = ecsVersion
+
+	if f.ServiceName != "" {
+		m["service.name"] = f.ServiceName
+	}
+	hostname := f.Hostname
+	if hostname == "" && f.DefaultHostname {
+		hostname = defaultHostname()
+	}
+	if hostname != "" {
+		m["host.hostname"] = hostname
+	}
+
+	if entry.HasCaller() {
+		m["log.origin.function"] = entry.Caller.Function
+		m["log.origin.file.name"] = entry.Caller.File
+		m["log.origin.file.line"] = entry.Caller.Line
+	}
+
+	err, isError := entry.Data[logrus.ErrorKey].(error)
+	if isError {
+		m["error.message"] = err.Error()
+		m["error.type"] = fmt.Sprintf("%T", err)
+		// the error with stack, e.g. github.com/pkg/errors
+		if stack := fmt.Sprintf("%+v", err); stack != err.Error() {
+			m["error.stack_trace"] = stack
+		}
+	}
+
+	for k, v := range entry.Data {
+		if isError && k == logrus.ErrorKey {
+			continue
+		}
+
+		// not to silently overwrite the ecs fields
+		if _, ok := m[k]; ok {
+			k = "fields." + k
+		}
+		m[k] = fieldValue(v)
+	}
+
+	buf, err := jsoniter.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
+	}
+	return append(buf, '\n'), nil
+}
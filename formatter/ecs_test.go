@@ -0,0 +1,88 @@
+package formatter
+
+import (
+	"encoding/json"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestECSFormatter(t *testing.T) {
+	f := &ECSFormatter{ServiceName: "api", Hostname: "localhost"}
+
+	entry := &logrus.Entry{
+		Message: "hello",
+		Level:   logrus.WarnLevel,
+		Time:    time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+		Data:    logrus.Fields{"a": 1, "message": "x"},
+	}
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, map[string]interface{}{
+		"@timestamp":     "2020-05-01T10:00:00Z",
+		"log.level":      "warning",
+		"message":        "hello",
+		"ecs.version":    ecsVersion,
+		"service.name":   "api",
+		"host.hostname":  "localhost",
+		"a":              float64(1),
+		"fields.message": "x",
+	}, m)
+}
+
+func TestECSFormatterError(t *testing.T) {
+	f := &ECSFormatter{}
+
+	// without stack
+	entry := &logrus.Entry{Data: logrus.Fields{"error": errors.New("oops"), "other": errors.New("wild walrus")}}
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "oops", m["error.message"])
+	assert.Equal(t, "*errors.errorString", m["error.type"])
+	assert.NotContains(t, m, "error.stack_trace")
+	assert.NotContains(t, m, "error")
+	assert.Equal(t, "wild walrus", m["other"])
+	assert.NotContains(t, m, "service.name")
+	assert.NotContains(t, m, "host.hostname")
+
+	// with stack
+	entry = &logrus.Entry{Data: logrus.Fields{"error": pkgerrors.New("oops")}}
+	b, err = f.Format(entry)
+	assert.NoError(t, err)
+
+	m = nil
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "oops", m["error.message"])
+	assert.Contains(t, m["error.stack_trace"], "TestECSFormatterError")
+}
+
+func TestECSFormatterCaller(t *testing.T) {
+	f := &ECSFormatter{}
+
+	entry := &logrus.Entry{
+		Logger: logrus.New(),
+		Data:   logrus.Fields{},
+		Caller: &runtime.Frame{Function: "main.main", File: "/src/main.go", Line: 42},
+	}
+	entry.Logger.ReportCaller = true
+
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "main.main", m["log.origin.function"])
+	assert.Equal(t, "/src/main.go", m["log.origin.file.name"])
+	assert.Equal(t, float64(42), m["log.origin.file.line"])
+}
@@ -0,0 +1,122 @@
+package formatter
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+
+	"github.com/pkg/errors"
+)
+
+// the max depth of the cause chain, to avoid the cycle
+const maxErrorDepth = 32
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// jsonError is the structured error, with the cause chain and the stack frames
+type jsonError struct {
+	Message string           `json:"message"`
+	Type    string           `json:"type"`
+	Stack   []jsonStackFrame `json:"stack,omitempty"`
+	Cause   interface{}      `json:"cause,omitempty"`
+}
+
+type jsonStackFrame struct {
+	Func string `json:"func"`
+	File string `json:"file"`
+	Line int    `json:"line"`
+}
+
+// richError converts the error into jsonError, or an array of them if it's a multi-error,
+// e.g. `logging.Errors`, the error with `Unwrap() []error` or `WrappedErrors() []error`
+func richError(err error) interface{} {
+	return newRichError(err, 0)
+}
+
+func newRichError(err error, depth int) interface{} {
+	if err == nil {
+		return nil
+	}
+
+	if errs, ok := multiErrors(err); ok {
+		items := make([]interface{}, 0, len(errs))
+		for _, e := range errs {
+			items = append(items, newRichError(e, depth+1))
+		}
+		return items
+	}
+
+	e := &jsonError{
+		Message: err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+		Stack:   stackFrames(err),
+	}
+
+	// the wrappers without new message, e.g. errors.WithStack, are merged into one
+	cause := unwrapError(err)
+	for cause != nil && cause.Error() == e.Message {
+		if e.Stack == nil {
+			e.Stack = stackFrames(cause)
+		}
+		cause = unwrapError(cause)
+	}
+
+	if cause != nil && depth < maxErrorDepth {
+		e.Cause = newRichError(cause, depth+1)
+	}
+	return e
+}
+
+// unwrapError returns the cause via `Unwrap() error` or `Cause() error` of github.com/pkg/errors
+func unwrapError(err error) error {
+	switch e := err.(type) {
+	case interface{ Unwrap() error }:
+		return e.Unwrap()
+	case interface{ Cause() error }:
+		return e.Cause()
+	}
+	return nil
+}
+
+func multiErrors(err error) ([]error, bool) {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		return e.Unwrap(), true
+	case interface{ WrappedErrors() []error }:
+		return e.WrappedErrors(), true
+	}
+
+	// the slice of errors, e.g. `logging.Errors`
+	v := reflect.ValueOf(err)
+	if v.Kind() != reflect.Slice || !v.Type().Elem().Implements(errorType) {
+		return nil, false
+	}
+	errs := make([]error, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		e, _ := v.Index(i).Interface().(error)
+		errs = append(errs, e)
+	}
+	return errs, true
+}
+
+// stackFrames returns the stack of the error created by github.com/pkg/errors
+func stackFrames(err error) []jsonStackFrame {
+	tracer, ok := err.(interface{ StackTrace() errors.StackTrace })
+	if !ok {
+		return nil
+	}
+
+	stack := tracer.StackTrace()
+	frames := make([]jsonStackFrame, 0, len(stack))
+	for _, f := range stack {
+		// the pc of frame is the return address, pc-1 is the call
+		pc := uintptr(f) - 1
+		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			continue
+		}
+		file, line := fn.FileLine(pc)
+		frames = append(frames, jsonStackFrame{Func: fn.Name(), File: file, Line: line})
+	}
+	return frames
+}
@@ -0,0 +1,88 @@
+package formatter
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testErrors []error
+
+func (e testErrors) Error() string {
+	return "multiple errors"
+}
+
+type testWrappedErrors struct {
+	errs []error
+}
+
+func (e testWrappedErrors) Error() string {
+	return "wrapped errors"
+}
+
+func (e testWrappedErrors) WrappedErrors() []error {
+	return e.errs
+}
+
+func TestRichErrorPlain(t *testing.T) {
+	e := richError(io.EOF)
+	assert.Equal(t, &jsonError{Message: "EOF", Type: "*errors.errorString"}, e)
+}
+
+func TestRichErrorStack(t *testing.T) {
+	e, ok := richError(pkgerrors.New("boom")).(*jsonError)
+	assert.True(t, ok)
+	assert.Equal(t, "boom", e.Message)
+	assert.Equal(t, "*errors.fundamental", e.Type)
+	assert.Nil(t, e.Cause)
+
+	if assert.NotEmpty(t, e.Stack) {
+		assert.True(t, strings.HasSuffix(e.Stack[0].Func, "TestRichErrorStack"))
+		assert.True(t, strings.HasSuffix(e.Stack[0].File, "error_test.go"))
+		assert.True(t, e.Stack[0].Line > 0)
+	}
+}
+
+func TestRichErrorCause(t *testing.T) {
+	// errors.Wrap is withStack{withMessage{cause}}, merged into one
+	err := pkgerrors.Wrap(io.EOF, "read")
+	e, ok := richError(err).(*jsonError)
+	assert.True(t, ok)
+	assert.Equal(t, "read: EOF", e.Message)
+	assert.Equal(t, "*errors.withStack", e.Type)
+	assert.NotEmpty(t, e.Stack)
+	assert.Equal(t, &jsonError{Message: "EOF", Type: "*errors.errorString"}, e.Cause)
+
+	// the std errors with Unwrap
+	err = pkgerrors.WithMessage(errors.New("origin"), "second")
+	e, ok = richError(err).(*jsonError)
+	assert.True(t, ok)
+	assert.Equal(t, "second: origin", e.Message)
+	assert.Empty(t, e.Stack)
+	assert.Equal(t, &jsonError{Message: "origin", Type: "*errors.errorString"}, e.Cause)
+}
+
+func TestRichErrorMulti(t *testing.T) {
+	err := testErrors{io.EOF, nil, pkgerrors.WithMessage(io.ErrUnexpectedEOF, "read")}
+	assert.Equal(t, []interface{}{
+		&jsonError{Message: "EOF", Type: "*errors.errorString"},
+		nil,
+		&jsonError{
+			Message: "read: unexpected EOF",
+			Type:    "*errors.withMessage",
+			Cause:   &jsonError{Message: "unexpected EOF", Type: "*errors.errorString"},
+		},
+	}, richError(err))
+
+	err2 := testWrappedErrors{errs: []error{io.EOF}}
+	assert.Equal(t, []interface{}{&jsonError{Message: "EOF", Type: "*errors.errorString"}}, richError(err2))
+
+	// as the cause
+	e, ok := richError(pkgerrors.WithMessage(testErrors{io.EOF}, "batch")).(*jsonError)
+	assert.True(t, ok)
+	assert.Equal(t, []interface{}{&jsonError{Message: "EOF", Type: "*errors.errorString"}}, e.Cause)
+}
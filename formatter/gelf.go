@@ -0,0 +1,102 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wklken/logging-go/internal/syslog"
+)
+
+const gelfVersion = "1.1"
+
+// GELFFormatter formats logs into GELF 1.1 json, like:
+//
+//	{"version": "1.1", "host": "...", "short_message": "...", "full_message": "...", "timestamp": 1588327200.123,
+//	 "level": 6, "_id": 1, ...}
+//
+// https://go2docs.graylog.org/current/getting_in_log_data/gelf.html
+type GELFFormatter struct {
+	// Hostname is the value of `host`, default is the hostname of the machine
+	Hostname string
+}
+
+// Format renders a single log entry
+func (f *GELFFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	hostname := f.Hostname
+	if hostname == "" {
+		hostname = defaultHostname()
+	}
+
+	m := make(map[string]interface{}, len(entry.Data)+8)
+	m["version"] = gelfVersion
+	m["host"] = hostname
+	m["timestamp"] = float64(entry.Time.UnixNano()/1e6) / 1e3
+	m["level"] = syslog.Severity(entry.Level)
+
+	// the short message is the first line, the full message is the whole message or the error with stack
+	short := strings.TrimSpace(entry.Message)
+	if i := strings.IndexByte(short, '\n'); i >= 0 {
+		short = strings.TrimSpace(short[:i])
+		m["full_message"] = entry.Message
+	}
+	if short == "" {
+		// the short_message is required
+		short = "-"
+	}
+	m["short_message"] = short
+
+	if err, ok := entry.Data[logrus.ErrorKey].(error); ok {
+		if stack := fmt.Sprintf("%+v", err); stack != err.Error() {
+			m["full_message"] = entry.Message + "\n" + stack
+		}
+	}
+
+	if entry.HasCaller() {
+		m["_file"] = entry.Caller.File
+		m["_line"] = entry.Caller.Line
+		m["_function"] = entry.Caller.Function
+	}
+
+	for k, v := range entry.Data {
+		m[gelfFieldName(k)] = gelfFieldValue(v)
+	}
+
+	buf, err := jsoniter.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
+	}
+	return append(buf, '\n'), nil
+}
+
+// gelfFieldName prefix the field with `_`, replace the characters not in `[\w\.\-]` with `_`,
+// and `_id` is reserved, will be `__id`
+func gelfFieldName(key string) string {
+	b := []byte("_" + key)
+	for i, c := range b {
+		if c == '_' || c == '.' || c == '-' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			continue
+		}
+		b[i] = '_'
+	}
+	if name := string(b); name != "_id" {
+		return name
+	}
+	return "__id"
+}
+
+// gelfFieldValue keeps the numbers, others will be string, the additional fields should be string or number
+func gelfFieldValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return v
+	case error:
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,81 @@
+package formatter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGELFFormatter(t *testing.T) {
+	f := &GELFFormatter{Hostname: "localhost"}
+
+	entry := &logrus.Entry{
+		Message: "hello",
+		Level:   logrus.WarnLevel,
+		Time:    time.Date(2020, 5, 1, 10, 0, 0, 123000000, time.UTC),
+		Data: logrus.Fields{
+			"id":      1,
+			"user":    "tom",
+			"ok":      true,
+			"a b":     1.5,
+			"error":   errors.New("oops"),
+			"latency": 2 * time.Second,
+		},
+	}
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, map[string]interface{}{
+		"version":       "1.1",
+		"host":          "localhost",
+		"short_message": "hello",
+		"timestamp":     1588327200.123,
+		"level":         float64(4),
+		"__id":          float64(1),
+		"_user":         "tom",
+		"_ok":           "true",
+		"_a_b":          1.5,
+		"_error":        "oops",
+		"_latency":      "2s",
+	}, m)
+}
+
+func TestGELFFormatterFullMessage(t *testing.T) {
+	f := &GELFFormatter{}
+
+	// multi-line message
+	b, err := f.Format(&logrus.Entry{Message: "first line\nsecond line", Data: logrus.Fields{}})
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "first line", m["short_message"])
+	assert.Equal(t, "first line\nsecond line", m["full_message"])
+	assert.Equal(t, defaultHostname(), m["host"])
+
+	// empty message
+	b, err = f.Format(&logrus.Entry{Data: logrus.Fields{}})
+	assert.NoError(t, err)
+
+	m = nil
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "-", m["short_message"])
+	assert.NotContains(t, m, "full_message")
+
+	// error with stack
+	b, err = f.Format(&logrus.Entry{Message: "fail", Data: logrus.Fields{"error": pkgerrors.New("oops")}})
+	assert.NoError(t, err)
+
+	m = nil
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "fail", m["short_message"])
+	assert.Contains(t, m["full_message"], "fail\noops\n")
+	assert.Contains(t, m["full_message"], "TestGELFFormatterFullMessage")
+}
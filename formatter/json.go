@@ -1,8 +1,10 @@
 package formatter
 
 import (
+	"bytes"
 	"fmt"
 	"runtime"
+	"sort"
 	"time"
 
 	jsoniter "github.com/json-iterator/go"
@@ -35,6 +37,21 @@ func (f FieldMap) resolve(key fieldKey) string {
 	return string(key)
 }
 
+// ParseFieldMap parses the settings `field_map_{key}` of the default fields, e.g. `field_map_msg: message`,
+// the keys are `time`, `level`, `msg`, `logrus_error`, `func` and `file`, nil if not set
+func ParseFieldMap(settings map[string]string) FieldMap {
+	var m FieldMap
+	for _, key := range []fieldKey{FieldKeyTime, FieldKeyLevel, FieldKeyMsg, FieldKeyLogrusError, FieldKeyFunc, FieldKeyFile} {
+		if name, ok := settings["field_map_"+string(key)]; ok && name != "" {
+			if m == nil {
+				m = FieldMap{}
+			}
+			m[key] = name
+		}
+	}
+	return m
+}
+
 // JSONFormatter formats logs into parsable json
 type JSONFormatter struct {
 	// TimestampFormat sets the format used for marshaling timestamps.
@@ -66,20 +83,64 @@ type JSONFormatter struct {
 
 	// PrettyPrint will indent all json logs
 	PrettyPrint bool
+
+	// SortKeys emits the keys in fixed order: time, level, msg, func, file, then the fields sorted by key,
+	// the entry is encoded directly, without building the intermediate map.
+	// (the insertion order of fields is not available, logrus.Fields is a map)
+	SortKeys bool
+
+	// SortingFunc can be set by the user to change the order of the field keys when SortKeys is enabled,
+	// default is sort.Strings
+	SortingFunc func([]string)
+
+	// RichErrors encodes the errors into objects with `message`, `type`, `stack` and the `cause` chain,
+	// the multi-errors, e.g. `logging.Errors`, will be arrays. default is the error message string
+	RichErrors bool
+
+	// ExpandDottedKeys expands the dotted keys into nested objects, e.g. `http.method` and `http.status`
+	// will be `{"http": {"method": ..., "status": ...}}`, the leaf value collides with an object is kept
+	// as `_value` of the object
+	ExpandDottedKeys bool
+
+	// FlattenNestedKeys flattens the nested map fields into dotted keys, e.g. `{"http": {"method": ...}}`
+	// will be `http.method`. if both enabled, the fields are flattened then expanded, which merges the
+	// map fields and the dotted keys
+	FlattenNestedKeys bool
+
+	// Limits limits the size of the message, the fields and the whole entry, the truncated values end with
+	// TruncatedMarker and `_truncated: true` is added
+	Limits Limits
 }
 
+// jsonPrettyAPI is jsoniter.ConfigDefault with indention
+var jsonPrettyAPI = jsoniter.Config{EscapeHTML: true, IndentionStep: 2}.Froze()
+
 // Format renders a single log entry
 func (f *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
-	data := make(logrus.Fields, len(entry.Data)+4)
-	for k, v := range entry.Data {
-		switch v := v.(type) {
-		case error:
-			// Otherwise errors are ignored by `encoding/json`
-			// https://github.com/sirupsen/logrus/issues/137
-			data[k] = v.Error()
-		default:
-			data[k] = v
-		}
+	if f.Limits.Enabled() {
+		return f.Limits.Format(entry, f.format)
+	}
+	return f.format(entry)
+}
+
+func (f *JSONFormatter) format(entry *logrus.Entry) ([]byte, error) {
+	if f.SortKeys {
+		return f.formatSorted(entry)
+	}
+
+	fields := entry.Data
+	if f.FlattenNestedKeys {
+		fields = flattenFields(fields)
+	}
+
+	data := make(logrus.Fields, len(fields)+4)
+	for k, v := range fields {
+		// Otherwise errors are ignored by `encoding/json`
+		// https://github.com/sirupsen/logrus/issues/137
+		data[k] = f.jsonValue(v)
+	}
+	if f.ExpandDottedKeys {
+		data = expandFields(data)
 	}
 
 	if f.DataKey != "" {
@@ -134,7 +195,11 @@ func (f *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	// 	return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
 	// }
 
-	buf, err := jsoniter.Marshal(data)
+	api := jsoniter.ConfigDefault
+	if f.PrettyPrint {
+		api = jsonPrettyAPI
+	}
+	buf, err := api.Marshal(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
 	}
@@ -144,15 +209,179 @@ func (f *JSONFormatter) Format(entry *logrus.Entry) ([]byte, error) {
 	// return b.Bytes(), nil
 }
 
+// formatSorted encodes the entry with the keys in fixed order,
+// ignore the entry.Buffer same as Format, the entry may be formatted by the async hooks after it's put back
+func (f *JSONFormatter) formatSorted(entry *logrus.Entry) ([]byte, error) {
+	b := &bytes.Buffer{}
+
+	api := jsoniter.ConfigDefault
+	if f.PrettyPrint {
+		api = jsonPrettyAPI
+	}
+	stream := api.BorrowStream(b)
+	defer api.ReturnStream(stream)
+
+	fields := entry.Data
+	if f.FlattenNestedKeys {
+		fields = flattenFields(fields)
+	}
+	if f.ExpandDottedKeys {
+		fields = expandFields(fields)
+	}
+
+	stream.WriteObjectStart()
+	first := true
+	writeField := func(key string, value interface{}) {
+		if !first {
+			stream.WriteMore()
+		}
+		first = false
+		stream.WriteObjectField(key)
+		f.writeValue(stream, value)
+	}
+
+	if !f.DisableTimestamp {
+		timestampFormat := f.TimestampFormat
+		if timestampFormat == "" {
+			timestampFormat = defaultTimestampFormat
+		}
+		writeField(f.FieldMap.resolve(FieldKeyTime), entry.Time.Format(timestampFormat))
+	}
+	writeField(f.FieldMap.resolve(FieldKeyLevel), entry.Level.String())
+	writeField(f.FieldMap.resolve(FieldKeyMsg), entry.Message)
+	if entry.HasCaller() {
+		funcVal := entry.Caller.Function
+		fileVal := fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+		if f.CallerPrettyfier != nil {
+			funcVal, fileVal = f.CallerPrettyfier(entry.Caller)
+		}
+		if funcVal != "" {
+			writeField(f.FieldMap.resolve(FieldKeyFunc), funcVal)
+		}
+		if fileVal != "" {
+			writeField(f.FieldMap.resolve(FieldKeyFile), fileVal)
+		}
+	}
+
+	if f.DataKey != "" {
+		stream.WriteMore()
+		stream.WriteObjectField(f.clashKey(f.DataKey, entry.HasCaller()))
+		f.writeObject(stream, fields)
+	} else {
+		// the fields clash with the default fields will be renamed to `fields.{key}`
+		var renamed map[string]string
+		keys := f.sortedKeys(fields, func(k string) string {
+			if key := f.clashKey(k, entry.HasCaller()); key != k {
+				if renamed == nil {
+					renamed = make(map[string]string, 1)
+				}
+				renamed[key] = k
+				return key
+			}
+			return k
+		})
+		for _, k := range keys {
+			if original, ok := renamed[k]; ok {
+				writeField(k, fields[original])
+			} else {
+				writeField(k, fields[k])
+			}
+		}
+	}
+
+	stream.WriteObjectEnd()
+	stream.WriteRaw("\n")
+	if stream.Error != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", stream.Error)
+	}
+	if err := stream.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
+	}
+	return b.Bytes(), nil
+}
+
+// writeObject writes the fields as an object with the sorted keys
+func (f *JSONFormatter) writeObject(stream *jsoniter.Stream, fields logrus.Fields) {
+	if len(fields) == 0 {
+		stream.WriteEmptyObject()
+		return
+	}
+
+	stream.WriteObjectStart()
+	for i, k := range f.sortedKeys(fields, nil) {
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteObjectField(k)
+		f.writeValue(stream, fields[k])
+	}
+	stream.WriteObjectEnd()
+}
+
+// writeValue writes the value, the objects expanded from the dotted keys are written with the sorted keys too
+func (f *JSONFormatter) writeValue(stream *jsoniter.Stream, value interface{}) {
+	if obj, ok := value.(nestedFields); ok {
+		f.writeObject(stream, logrus.Fields(obj))
+		return
+	}
+	stream.WriteVal(f.jsonValue(value))
+}
+
+// sortedKeys returns the keys of fields(renamed if rename is not nil), sorted by SortingFunc or sort.Strings
+func (f *JSONFormatter) sortedKeys(data logrus.Fields, rename func(string) string) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		if rename != nil {
+			k = rename(k)
+		}
+		keys = append(keys, k)
+	}
+	if f.SortingFunc != nil {
+		f.SortingFunc(keys)
+	} else {
+		sort.Strings(keys)
+	}
+	return keys
+}
+
+// clashKey returns `fields.{key}` if the key clashes with the default fields, same as prefixFieldClashes
+func (f *JSONFormatter) clashKey(key string, reportCaller bool) string {
+	switch key {
+	case f.FieldMap.resolve(FieldKeyTime), f.FieldMap.resolve(FieldKeyMsg),
+		f.FieldMap.resolve(FieldKeyLevel), f.FieldMap.resolve(FieldKeyLogrusError):
+		return "fields." + key
+	}
+	if reportCaller {
+		switch key {
+		case f.FieldMap.resolve(FieldKeyFunc), f.FieldMap.resolve(FieldKeyFile):
+			return "fields." + key
+		}
+	}
+	return key
+}
+
+// jsonValue converts the error into string or the structured error if RichErrors is enabled,
+// otherwise errors are ignored by `encoding/json`
+func (f *JSONFormatter) jsonValue(v interface{}) interface{} {
+	err, ok := v.(error)
+	if !ok {
+		return v
+	}
+	if f.RichErrors {
+		return richError(err)
+	}
+	return err.Error()
+}
+
 // This is to not silently overwrite `time`, `msg`, `func` and `level` fields when
 // dumping it. If this code wasn't there doing:
 //
-//  logrus.WithField("level", 1).Info("hello")
+//	logrus.WithField("level", 1).Info("hello")
 //
 // Would just silently drop the user provided level. Instead with this code
 // it'll logged as:
 //
-//  {"level": "info", "fields.level": 1, "msg": "hello", "time": "..."}
+//	{"level": "info", "fields.level": 1, "msg": "hello", "time": "..."}
 //
 // It's not exported because it's still using Data in an opinionated way. It's to
 // avoid code duplication between the two default formatters.
@@ -1,12 +1,18 @@
 package formatter
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"runtime"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
+	pkgerrors "github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
@@ -289,3 +295,221 @@ func TestJSONEnableTimestamp(t *testing.T) {
 	s := string(b)
 	assert.Contains(t, s, FieldKeyTime)
 }
+
+func newJSONTestEntry(fields logrus.Fields) *logrus.Entry {
+	entry := logrus.WithFields(fields)
+	entry.Time = time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	entry.Level = logrus.InfoLevel
+	entry.Message = "hello"
+	return entry
+}
+
+func TestJSONSortKeys(t *testing.T) {
+	t.Parallel()
+
+	formatter := &JSONFormatter{SortKeys: true}
+
+	var data = []struct {
+		fields   logrus.Fields
+		expected string
+	}{
+		{logrus.Fields{}, `{"time":"2020-05-01T10:00:00Z","level":"info","msg":"hello"}`},
+		{logrus.Fields{"c": 3, "a": "x", "b": []int{1, 2}}, `{"time":"2020-05-01T10:00:00Z","level":"info","msg":"hello","a":"x","b":[1,2],"c":3}`},
+		{logrus.Fields{"error": errors.New("wild walrus")}, `{"time":"2020-05-01T10:00:00Z","level":"info","msg":"hello","error":"wild walrus"}`},
+		// clash with the default fields
+		{logrus.Fields{"msg": "x", "level": 1, "z": 2}, `{"time":"2020-05-01T10:00:00Z","level":"info","msg":"hello","fields.level":1,"fields.msg":"x","z":2}`},
+	}
+	for _, d := range data {
+		b, err := formatter.Format(newJSONTestEntry(d.fields))
+		assert.NoError(t, err)
+		assert.Equal(t, d.expected+"\n", string(b))
+	}
+}
+
+func TestJSONSortKeysOptions(t *testing.T) {
+	t.Parallel()
+
+	// the same options as the map
+	formatter := &JSONFormatter{
+		SortKeys:         true,
+		DisableTimestamp: true,
+		DataKey:          "args",
+		FieldMap:         FieldMap{FieldKeyMsg: "message"},
+	}
+	b, err := formatter.Format(newJSONTestEntry(logrus.Fields{"b": 2, "a": 1}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","message":"hello","args":{"a":1,"b":2}}`+"\n", string(b))
+
+	b, err = formatter.Format(newJSONTestEntry(logrus.Fields{}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","message":"hello","args":{}}`+"\n", string(b))
+
+	// sorting func
+	formatter = &JSONFormatter{
+		SortKeys:         true,
+		DisableTimestamp: true,
+		SortingFunc: func(keys []string) {
+			sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+		},
+	}
+	b, err = formatter.Format(newJSONTestEntry(logrus.Fields{"a": 1, "b": 2}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"hello","b":2,"a":1}`+"\n", string(b))
+
+	// caller
+	entry := newJSONTestEntry(logrus.Fields{"file": "x"})
+	entry.Logger = logrus.New()
+	entry.Logger.ReportCaller = true
+	entry.Caller = &runtime.Frame{Function: "main.main", File: "/src/main.go", Line: 42}
+	b, err = formatter.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"hello","func":"main.main","file":"/src/main.go:42","fields.file":"x"}`+"\n", string(b))
+}
+
+func TestJSONSortKeysIgnoreBuffer(t *testing.T) {
+	t.Parallel()
+
+	formatter := &JSONFormatter{SortKeys: true}
+
+	// the entry.Buffer is not used, it may be put back into the pool while the async hooks format the entry
+	entry := newJSONTestEntry(logrus.Fields{"a": 1})
+	entry.Buffer = &bytes.Buffer{}
+	b, err := formatter.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"time":"2020-05-01T10:00:00Z","level":"info","msg":"hello","a":1}`+"\n", string(b))
+	assert.Equal(t, 0, entry.Buffer.Len())
+
+	// unsupported value
+	_, err = formatter.Format(newJSONTestEntry(logrus.Fields{"ch": make(chan int)}))
+	assert.Error(t, err)
+}
+
+func TestJSONPrettyPrint(t *testing.T) {
+	t.Parallel()
+
+	expected := `{
+  "time": "2020-05-01T10:00:00Z",
+  "level": "info",
+  "msg": "hello",
+  "a": {
+    "b": 1
+  }
+}
+`
+	formatter := &JSONFormatter{SortKeys: true, PrettyPrint: true}
+	b, err := formatter.Format(newJSONTestEntry(logrus.Fields{"a": map[string]int{"b": 1}}))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, string(b))
+
+	// without SortKeys
+	formatter = &JSONFormatter{PrettyPrint: true}
+	b, err = formatter.Format(newJSONTestEntry(logrus.Fields{}))
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "{\n  \"")
+	assert.True(t, json.Valid(b))
+}
+
+func TestJSONRichErrors(t *testing.T) {
+	fields := logrus.Fields{"error": pkgerrors.Wrap(io.EOF, "read"), "errors": testErrors{io.EOF}, "foo": "bar"}
+
+	for _, sortKeys := range []bool{false, true} {
+		formatter := &JSONFormatter{RichErrors: true, SortKeys: sortKeys}
+		b, err := formatter.Format(newJSONTestEntry(fields))
+		assert.NoError(t, err)
+
+		entry := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(b, &entry))
+		assert.Equal(t, "bar", entry["foo"])
+
+		e, ok := entry["error"].(map[string]interface{})
+		if assert.True(t, ok) {
+			assert.Equal(t, "read: EOF", e["message"])
+			assert.Equal(t, "*errors.withStack", e["type"])
+			assert.NotEmpty(t, e["stack"])
+			assert.Equal(t, map[string]interface{}{"message": "EOF", "type": "*errors.errorString"}, e["cause"])
+		}
+		assert.Equal(t, []interface{}{map[string]interface{}{"message": "EOF", "type": "*errors.errorString"}}, entry["errors"])
+	}
+
+	// default is the message
+	b, err := (&JSONFormatter{}).Format(newJSONTestEntry(fields))
+	assert.NoError(t, err)
+	entry := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	assert.Equal(t, "read: EOF", entry["error"])
+	assert.Equal(t, "multiple errors", entry["errors"])
+}
+
+func TestJSONExpandDottedKeys(t *testing.T) {
+	fields := logrus.Fields{"http.method": "GET", "http.status": 200, "http": "x", "foo": "bar"}
+
+	formatter := &JSONFormatter{ExpandDottedKeys: true, DisableTimestamp: true, SortKeys: true}
+	b, err := formatter.Format(newJSONTestEntry(fields))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"hello","foo":"bar","http":{"_value":"x","method":"GET","status":200}}`+"\n", string(b))
+
+	formatter = &JSONFormatter{ExpandDottedKeys: true}
+	b, err = formatter.Format(newJSONTestEntry(logrus.Fields{"http.method": "GET", "level.x": 1, "error.message": errors.New("e")}))
+	assert.NoError(t, err)
+	entry := make(map[string]interface{})
+	assert.NoError(t, json.Unmarshal(b, &entry))
+	assert.Equal(t, map[string]interface{}{"method": "GET"}, entry["http"])
+	assert.Equal(t, map[string]interface{}{"x": float64(1)}, entry["fields.level"])
+	// the error in the nested object
+	assert.Equal(t, map[string]interface{}{"message": "e"}, entry["error"])
+
+	formatter = &JSONFormatter{ExpandDottedKeys: true, DataKey: "data", SortKeys: true, DisableTimestamp: true}
+	b, err = formatter.Format(newJSONTestEntry(logrus.Fields{"a.b": 1}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"hello","data":{"a":{"b":1}}}`+"\n", string(b))
+}
+
+func TestJSONFlattenNestedKeys(t *testing.T) {
+	fields := logrus.Fields{"http": map[string]interface{}{"method": "GET", "err": errors.New("e")}, "foo": "bar"}
+
+	for _, sortKeys := range []bool{false, true} {
+		formatter := &JSONFormatter{FlattenNestedKeys: true, SortKeys: sortKeys}
+		b, err := formatter.Format(newJSONTestEntry(fields))
+		assert.NoError(t, err)
+
+		entry := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(b, &entry))
+		assert.Equal(t, "GET", entry["http.method"])
+		assert.Equal(t, "e", entry["http.err"])
+		assert.Equal(t, "bar", entry["foo"])
+		assert.NotContains(t, entry, "http")
+	}
+
+	// flatten then expand, the map fields and the dotted keys are merged
+	formatter := &JSONFormatter{FlattenNestedKeys: true, ExpandDottedKeys: true, SortKeys: true, DisableTimestamp: true}
+	b, err := formatter.Format(newJSONTestEntry(logrus.Fields{"http": map[string]interface{}{"method": "GET"}, "http.status": 200}))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"level":"info","msg":"hello","http":{"method":"GET","status":200}}`+"\n", string(b))
+}
+
+func TestJSONLimits(t *testing.T) {
+	fields := logrus.Fields{"body": strings.Repeat("a", 100), "a": 1}
+
+	for _, sortKeys := range []bool{false, true} {
+		formatter := &JSONFormatter{SortKeys: sortKeys, Limits: Limits{MaxMessageLength: 2, MaxFieldLength: 10, MaxEntrySize: 1000}}
+		entry := newJSONTestEntry(fields)
+		entry.Buffer = &bytes.Buffer{}
+		b, err := formatter.Format(entry)
+		assert.NoError(t, err)
+
+		m := make(map[string]interface{})
+		assert.NoError(t, json.Unmarshal(b, &m))
+		assert.Equal(t, "he"+TruncatedMarker, m["msg"])
+		assert.Equal(t, "aaaaaaaaaa"+TruncatedMarker, m["body"])
+		assert.Equal(t, float64(1), m["a"])
+		assert.Equal(t, true, m["_truncated"])
+		assert.Equal(t, "hello", entry.Message)
+	}
+}
+
+func TestParseFieldMap(t *testing.T) {
+	assert.Nil(t, ParseFieldMap(nil))
+	assert.Nil(t, ParseFieldMap(map[string]string{"field_map_msg": "", "field_map_other": "a"}))
+	assert.Equal(t, FieldMap{FieldKeyMsg: "message", FieldKeyLevel: "severity"},
+		ParseFieldMap(map[string]string{"field_map_msg": "message", "field_map_level": "severity"}))
+}
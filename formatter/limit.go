@@ -0,0 +1,242 @@
+package formatter
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// FieldKeyTruncated is added with value true if anything of the entry is truncated
+	FieldKeyTruncated = "_truncated"
+	// TruncatedMarker is appended to the truncated strings, and replaces the dropped values
+	TruncatedMarker = "...[truncated]"
+)
+
+// Limits limits the size of the entry, the zero value means no limit
+type Limits struct {
+	// MaxMessageLength is the max bytes of the message
+	MaxMessageLength int
+	// MaxFieldLength is the max bytes of the string values and the error messages, the nested ones included
+	MaxFieldLength int
+	// MaxFields is the max number of fields, the fields are kept in sorted order of key
+	MaxFields int
+	// MaxDepth is the max nesting levels of the map and slice values, the deeper ones are replaced by the marker
+	MaxDepth int
+	// MaxEntrySize is the max bytes of the serialized entry, the largest fields are replaced by the marker,
+	// then the message is truncated until the entry fits
+	MaxEntrySize int
+}
+
+// ParseLimits parses the settings `max_message_length`, `max_field_length`, `max_fields`, `max_depth`
+// and `max_entry_size`
+func ParseLimits(settings map[string]string) (Limits, error) {
+	var l Limits
+	for key, p := range map[string]*int{
+		"max_message_length": &l.MaxMessageLength,
+		"max_field_length":   &l.MaxFieldLength,
+		"max_fields":         &l.MaxFields,
+		"max_depth":          &l.MaxDepth,
+		"max_entry_size":     &l.MaxEntrySize,
+	} {
+		value, ok := settings[key]
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 {
+			return Limits{}, fmt.Errorf("%s should be non-negative integer", key)
+		}
+		*p = i
+	}
+	return l, nil
+}
+
+// Enabled returns true if any limit is set
+func (l Limits) Enabled() bool {
+	return l != Limits{}
+}
+
+// Format truncates the entry then formats it, the entry is not modified
+func (l Limits) Format(entry *logrus.Entry, format func(*logrus.Entry) ([]byte, error)) ([]byte, error) {
+	e, truncated := l.Truncate(entry)
+	out, err := format(e)
+	if err != nil || l.MaxEntrySize <= 0 || len(out) <= l.MaxEntrySize {
+		return out, err
+	}
+
+	// the copy to be truncated, the buffer of the entry has been used
+	if !truncated {
+		e = copyEntry(entry, entry.Message, entry.Data)
+	}
+	e.Buffer = nil
+
+	// replace the largest fields by the marker
+	excess := len(out) - l.MaxEntrySize
+	if _, ok := e.Data[FieldKeyTruncated]; !ok {
+		excess += len(`,"":true`) + len(FieldKeyTruncated)
+	}
+	data := make(logrus.Fields, len(e.Data)+1)
+	sizes := make(map[string]int, len(e.Data))
+	keys := make([]string, 0, len(e.Data))
+	for k, v := range e.Data {
+		data[k] = v
+		if k == FieldKeyTruncated {
+			continue
+		}
+		b, err := jsoniter.Marshal(v)
+		if err != nil {
+			continue
+		}
+		sizes[k] = len(b)
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sizes[keys[i]] != sizes[keys[j]] {
+			return sizes[keys[i]] > sizes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	markerSize := len(TruncatedMarker) + 2
+	for _, k := range keys {
+		if excess <= 0 || sizes[k] <= markerSize {
+			break
+		}
+		data[k] = TruncatedMarker
+		excess -= sizes[k] - markerSize
+	}
+	data[FieldKeyTruncated] = true
+	e.Data = data
+
+	// then the message, the escaping of json may make the bytes differ, so try a few times
+	for i := 0; i < 3; i++ {
+		if out, err = format(e); err != nil || len(out) <= l.MaxEntrySize || e.Message == "" {
+			return out, err
+		}
+		n := len(e.Message) - (len(out) - l.MaxEntrySize) - len(TruncatedMarker)
+		e.Message = truncateString(e.Message, n)
+	}
+	// the default fields can not be truncated, keep the best effort
+	return format(e)
+}
+
+// Truncate returns the copy of the entry with the limits applied except MaxEntrySize, and true if anything truncated,
+// the entry itself is returned if nothing truncated
+func (l Limits) Truncate(entry *logrus.Entry) (*logrus.Entry, bool) {
+	truncated := false
+
+	message := entry.Message
+	if l.MaxMessageLength > 0 && len(message) > l.MaxMessageLength {
+		message = truncateString(message, l.MaxMessageLength)
+		truncated = true
+	}
+
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		keys = append(keys, k)
+	}
+	if l.MaxFields > 0 && len(keys) > l.MaxFields {
+		sort.Strings(keys)
+		keys = keys[:l.MaxFields]
+		truncated = true
+	}
+
+	data := make(logrus.Fields, len(keys)+1)
+	for _, k := range keys {
+		v, changed := l.truncateValue(entry.Data[k], 0)
+		data[k] = v
+		truncated = truncated || changed
+	}
+
+	if !truncated {
+		return entry, false
+	}
+	data[FieldKeyTruncated] = true
+	return copyEntry(entry, message, data), true
+}
+
+func (l Limits) truncateValue(value interface{}, depth int) (interface{}, bool) {
+	switch v := value.(type) {
+	case nil:
+		return v, false
+	case string:
+		if l.MaxFieldLength > 0 && len(v) > l.MaxFieldLength {
+			return truncateString(v, l.MaxFieldLength), true
+		}
+		return v, false
+	case []byte:
+		return v, false
+	case error:
+		if msg := v.Error(); l.MaxFieldLength > 0 && len(msg) > l.MaxFieldLength {
+			return truncateString(msg, l.MaxFieldLength), true
+		}
+		return v, false
+	}
+
+	if l.MaxFieldLength <= 0 && l.MaxDepth <= 0 {
+		return value, false
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value, false
+		}
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return TruncatedMarker, true
+		}
+		m := make(map[string]interface{}, rv.Len())
+		changed := false
+		iter := rv.MapRange()
+		for iter.Next() {
+			x, c := l.truncateValue(iter.Value().Interface(), depth+1)
+			m[iter.Key().String()] = x
+			changed = changed || c
+		}
+		if changed {
+			return m, true
+		}
+	case reflect.Slice, reflect.Array:
+		if l.MaxDepth > 0 && depth >= l.MaxDepth {
+			return TruncatedMarker, true
+		}
+		s := make([]interface{}, rv.Len())
+		changed := false
+		for i := 0; i < rv.Len(); i++ {
+			x, c := l.truncateValue(rv.Index(i).Interface(), depth+1)
+			s[i] = x
+			changed = changed || c
+		}
+		if changed {
+			return s, true
+		}
+	}
+	return value, false
+}
+
+// truncateString cuts the string to n bytes at most, not in the middle of a rune, and appends the marker
+func truncateString(s string, n int) string {
+	if n < 0 {
+		n = 0
+	}
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + TruncatedMarker
+}
+
+func copyEntry(entry *logrus.Entry, message string, data logrus.Fields) *logrus.Entry {
+	e := *entry
+	e.Message = message
+	e.Data = data
+	return &e
+}
@@ -0,0 +1,111 @@
+package formatter
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLimits(t *testing.T) {
+	l, err := ParseLimits(map[string]string{})
+	assert.NoError(t, err)
+	assert.False(t, l.Enabled())
+
+	l, err = ParseLimits(map[string]string{
+		"max_message_length": "1", "max_field_length": "2", "max_fields": "3", "max_depth": "4", "max_entry_size": "5",
+	})
+	assert.NoError(t, err)
+	assert.True(t, l.Enabled())
+	assert.Equal(t, Limits{MaxMessageLength: 1, MaxFieldLength: 2, MaxFields: 3, MaxDepth: 4, MaxEntrySize: 5}, l)
+
+	_, err = ParseLimits(map[string]string{"max_fields": "a"})
+	assert.Error(t, err)
+	_, err = ParseLimits(map[string]string{"max_depth": "-1"})
+	assert.Error(t, err)
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "abc", truncateString("abc", 3))
+	assert.Equal(t, "ab"+TruncatedMarker, truncateString("abc", 2))
+	assert.Equal(t, TruncatedMarker, truncateString("abc", -1))
+	// not in the middle of a rune
+	assert.Equal(t, "a"+TruncatedMarker, truncateString("a中文", 2))
+	assert.Equal(t, "a中"+TruncatedMarker, truncateString("a中文", 4))
+}
+
+func TestLimitsTruncate(t *testing.T) {
+	entry := &logrus.Entry{Message: "hello", Data: logrus.Fields{"a": "abc"}}
+
+	// nothing truncated, the entry itself
+	e, truncated := Limits{MaxMessageLength: 10, MaxFieldLength: 10, MaxFields: 2, MaxDepth: 2}.Truncate(entry)
+	assert.False(t, truncated)
+	assert.True(t, e == entry)
+
+	entry = &logrus.Entry{
+		Message: "hello world",
+		Data: logrus.Fields{
+			"body":   strings.Repeat("a", 20),
+			"err":    errors.New(strings.Repeat("e", 20)),
+			"nested": map[string]interface{}{"s": strings.Repeat("b", 20), "deep": map[string]int{"x": 1}},
+			"list":   []interface{}{"c", []int{1}},
+			"count":  1,
+			"z":      "dropped",
+		},
+	}
+	e, truncated = Limits{MaxMessageLength: 5, MaxFieldLength: 4, MaxFields: 5, MaxDepth: 1}.Truncate(entry)
+	assert.True(t, truncated)
+	assert.Equal(t, "hello"+TruncatedMarker, e.Message)
+	assert.Equal(t, logrus.Fields{
+		"body":            "aaaa" + TruncatedMarker,
+		"count":           1,
+		"err":             "eeee" + TruncatedMarker,
+		"list":            []interface{}{"c", TruncatedMarker},
+		"nested":          map[string]interface{}{"s": "bbbb" + TruncatedMarker, "deep": TruncatedMarker},
+		FieldKeyTruncated: true,
+	}, e.Data)
+
+	// the entry is not modified
+	assert.Equal(t, "hello world", entry.Message)
+	assert.Len(t, entry.Data, 6)
+}
+
+func TestLimitsFormatEntrySize(t *testing.T) {
+	formatter := &JSONFormatter{DisableTimestamp: true, SortKeys: true}
+	entry := newJSONTestEntry(logrus.Fields{
+		"big":   strings.Repeat("a", 200),
+		"small": "b",
+		"mid":   strings.Repeat("c", 50),
+	})
+
+	// the largest field is replaced
+	l := Limits{MaxEntrySize: 150}
+	b, err := l.Format(entry, formatter.format)
+	assert.NoError(t, err)
+	assert.True(t, len(b) <= 150, string(b))
+	assert.Equal(t, `{"level":"info","msg":"hello","_truncated":true,"big":"...[truncated]","mid":"`+
+		strings.Repeat("c", 50)+`","small":"b"}`+"\n", string(b))
+
+	// then the message
+	entry.Message = strings.Repeat("m", 200)
+	l = Limits{MaxEntrySize: 120}
+	b, err = l.Format(entry, formatter.format)
+	assert.NoError(t, err)
+	assert.True(t, len(b) <= 120, string(b))
+	m := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.True(t, strings.HasSuffix(m["msg"].(string), TruncatedMarker))
+	assert.Equal(t, TruncatedMarker, m["big"])
+	assert.Equal(t, TruncatedMarker, m["mid"])
+	assert.Equal(t, "b", m["small"])
+	assert.Equal(t, true, m[FieldKeyTruncated])
+
+	// fits, not changed
+	entry.Message = "hello"
+	b, err = Limits{MaxEntrySize: 1000}.Format(entry, formatter.format)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), FieldKeyTruncated)
+}
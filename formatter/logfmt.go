@@ -0,0 +1,131 @@
+package formatter
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LogfmtFormatter formats logs into logfmt, like:
+//
+//	time=2020-05-01T10:00:00Z level=info msg="hello world" id=1
+//
+// the built-in keys come first(time, level, msg, func, file), then the fields sorted by key.
+type LogfmtFormatter struct {
+	// TimestampFormat sets the format used for marshaling timestamps.
+	TimestampFormat string
+
+	// DisableTimestamp allows disabling automatic timestamps in output
+	DisableTimestamp bool
+
+	// FieldMap allows users to customize the names of keys for default fields, same as JSONFormatter
+	FieldMap FieldMap
+
+	// CallerPrettyfier can be set by the user to modify the content
+	// of the function and file keys when ReportCaller is activated.
+	// If any of the returned value is the empty string the corresponding key will be removed.
+	CallerPrettyfier func(*runtime.Frame) (function string, file string)
+
+	// SortingFunc can be set by the user to change the order of the field keys, default is sort.Strings
+	SortingFunc func([]string)
+}
+
+// Format renders a single log entry
+func (f *LogfmtFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	prefixFieldClashes(data, f.FieldMap, entry.HasCaller())
+
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	if f.SortingFunc != nil {
+		f.SortingFunc(keys)
+	} else {
+		sort.Strings(keys)
+	}
+
+	// ignore the entry.Buffer, same as JSONFormatter, the entry may be formatted by the async hooks
+	// after logrus has put the pooled buffer back
+	b := &bytes.Buffer{}
+
+	if !f.DisableTimestamp {
+		timestampFormat := f.TimestampFormat
+		if timestampFormat == "" {
+			timestampFormat = defaultTimestampFormat
+		}
+		appendLogfmtKeyValue(b, f.FieldMap.resolve(FieldKeyTime), entry.Time.Format(timestampFormat))
+	}
+	appendLogfmtKeyValue(b, f.FieldMap.resolve(FieldKeyLevel), entry.Level.String())
+	appendLogfmtKeyValue(b, f.FieldMap.resolve(FieldKeyMsg), entry.Message)
+	if entry.HasCaller() {
+		funcVal := entry.Caller.Function
+		fileVal := fmt.Sprintf("%s:%d", entry.Caller.File, entry.Caller.Line)
+		if f.CallerPrettyfier != nil {
+			funcVal, fileVal = f.CallerPrettyfier(entry.Caller)
+		}
+		if funcVal != "" {
+			appendLogfmtKeyValue(b, f.FieldMap.resolve(FieldKeyFunc), funcVal)
+		}
+		if fileVal != "" {
+			appendLogfmtKeyValue(b, f.FieldMap.resolve(FieldKeyFile), fileVal)
+		}
+	}
+
+	for _, k := range keys {
+		appendLogfmtKeyValue(b, k, data[k])
+	}
+	b.WriteByte('\n')
+
+	return b.Bytes(), nil
+}
+
+func appendLogfmtKeyValue(b *bytes.Buffer, key string, value interface{}) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(logfmtKey(key))
+	b.WriteByte('=')
+
+	var s string
+	switch v := value.(type) {
+	case nil:
+		s = "null"
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case error:
+		s = v.Error()
+	default:
+		s = fmt.Sprint(v)
+	}
+	b.WriteString(quoteIfNeeded(s))
+}
+
+// logfmtKey replaces the characters not allowed in the key with `_`, the key can't be quoted
+func logfmtKey(key string) string {
+	if key == "" {
+		return "_"
+	}
+
+	var b []byte
+	for i, c := range key {
+		if c <= ' ' || c == '=' || c == '"' || c == 0x7f {
+			if b == nil {
+				b = []byte(key)
+			}
+			b[i] = '_'
+		}
+	}
+	if b == nil {
+		return key
+	}
+	return string(b)
+}
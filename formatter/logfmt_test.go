@@ -0,0 +1,99 @@
+package formatter
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLogfmtTestEntry(msg string, fields logrus.Fields) *logrus.Entry {
+	entry := logrus.WithFields(fields)
+	entry.Time = time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	entry.Level = logrus.InfoLevel
+	entry.Message = msg
+	return entry
+}
+
+func TestLogfmtFormatter(t *testing.T) {
+	f := &LogfmtFormatter{}
+
+	var data = []struct {
+		msg      string
+		fields   logrus.Fields
+		expected string
+	}{
+		{"hello", logrus.Fields{}, "time=2020-05-01T10:00:00Z level=info msg=hello\n"},
+		{"hello world", logrus.Fields{"b": 2, "a": true, "c": 1.5}, `time=2020-05-01T10:00:00Z level=info msg="hello world" a=true b=2 c=1.5` + "\n"},
+		// quoting and escaping
+		{"", logrus.Fields{"empty": "", "eq": "a=b", "quote": `say "hi"`, "newline": "a\nb", "nil": nil}, `time=2020-05-01T10:00:00Z level=info msg="" empty="" eq="a=b" newline="a\nb" nil=null quote="say \"hi\""` + "\n"},
+		{"hello", logrus.Fields{"unicode": "你好", "tab": "a\tb", "bytes": []byte("abc")}, `time=2020-05-01T10:00:00Z level=info msg=hello bytes=abc tab="a\tb" unicode=你好` + "\n"},
+		// error
+		{"fail", logrus.Fields{"error": errors.New("wild walrus")}, `time=2020-05-01T10:00:00Z level=info msg=fail error="wild walrus"` + "\n"},
+		// invalid key
+		{"hello", logrus.Fields{"a b": 1, "c=d": 2}, "time=2020-05-01T10:00:00Z level=info msg=hello a_b=1 c_d=2\n"},
+		// clash with the built-in keys
+		{"hello", logrus.Fields{"level": 1, "msg": "x"}, "time=2020-05-01T10:00:00Z level=info msg=hello fields.level=1 fields.msg=x\n"},
+	}
+	for _, d := range data {
+		b, err := f.Format(newLogfmtTestEntry(d.msg, d.fields))
+		assert.NoError(t, err)
+		assert.Equal(t, d.expected, string(b))
+	}
+}
+
+func TestLogfmtFormatterFieldMap(t *testing.T) {
+	f := &LogfmtFormatter{
+		TimestampFormat: time.RFC3339Nano,
+		FieldMap: FieldMap{
+			FieldKeyTime:  "ts",
+			FieldKeyLevel: "lvl",
+			FieldKeyMsg:   "message",
+		},
+	}
+
+	b, err := f.Format(newLogfmtTestEntry("hello", logrus.Fields{"ts": 1}))
+	assert.NoError(t, err)
+	assert.Equal(t, "ts=2020-05-01T10:00:00Z lvl=info message=hello fields.ts=1\n", string(b))
+
+	f = &LogfmtFormatter{DisableTimestamp: true}
+	b, err = f.Format(newLogfmtTestEntry("hello", logrus.Fields{}))
+	assert.NoError(t, err)
+	assert.Equal(t, "level=info msg=hello\n", string(b))
+}
+
+func TestLogfmtFormatterSortingFunc(t *testing.T) {
+	f := &LogfmtFormatter{
+		DisableTimestamp: true,
+		SortingFunc: func(keys []string) {
+			sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+		},
+	}
+
+	b, err := f.Format(newLogfmtTestEntry("hello", logrus.Fields{"a": 1, "b": 2, "c": 3}))
+	assert.NoError(t, err)
+	assert.Equal(t, "level=info msg=hello c=3 b=2 a=1\n", string(b))
+}
+
+func TestLogfmtFormatterCaller(t *testing.T) {
+	entry := newLogfmtTestEntry("hello", logrus.Fields{})
+	entry.Logger = logrus.New()
+	entry.Logger.ReportCaller = true
+	entry.Caller = &runtime.Frame{Function: "main.main", File: "/src/main.go", Line: 42}
+
+	f := &LogfmtFormatter{DisableTimestamp: true}
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "level=info msg=hello func=main.main file=/src/main.go:42\n", string(b))
+
+	f.CallerPrettyfier = func(frame *runtime.Frame) (string, string) {
+		return "", "main.go:42"
+	}
+	b, err = f.Format(entry)
+	assert.NoError(t, err)
+	assert.Equal(t, "level=info msg=hello file=main.go:42\n", string(b))
+}
@@ -0,0 +1,113 @@
+package formatter
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// LogstashV0 is the layout of logstash event v0, the fields are in `@fields`
+	LogstashV0 = "v0"
+	// LogstashV1 is the layout of logstash event v1, the fields are at the top level
+	LogstashV1 = "v1"
+)
+
+var (
+	hostnameOnce sync.Once
+	hostname     string
+)
+
+// defaultHostname returns the hostname of the machine, resolved only once
+func defaultHostname() string {
+	hostnameOnce.Do(func() {
+		hostname, _ = os.Hostname()
+	})
+	return hostname
+}
+
+// LogstashFormatter formats logs into the json event of logstash, v0:
+//
+//	{"@timestamp": "...", "@source_host": "...", "@message": "...", "@fields": {"level": "info", "application": "...", ...}}
+//
+// v1:
+//
+//	{"@timestamp": "...", "host": "...", "message": "...", "level": "info", "application": "...", ...}
+type LogstashFormatter struct {
+	// Version is the layout, LogstashV0 or LogstashV1(default)
+	Version string
+
+	// Application is the value of `application` field
+	Application string
+
+	// Hostname is the value of `@source_host`/`host` field
+	Hostname string
+
+	// DefaultHostname uses the hostname of the machine if Hostname is empty
+	DefaultHostname bool
+}
+
+// Format renders a single log entry
+func (f *LogstashFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	hostname := f.Hostname
+	if hostname == "" && f.DefaultHostname {
+		hostname = defaultHostname()
+	}
+
+	var m map[string]interface{}
+	if f.Version == LogstashV0 {
+		m = createLogstashV0Message(entry, f.Application, hostname)
+	} else {
+		m = createLogstashV1Message(entry, f.Application, hostname)
+	}
+
+	buf, err := jsoniter.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal fields to JSON, %v", err)
+	}
+	return append(buf, '\n'), nil
+}
+
+func createLogstashV0Message(entry *logrus.Entry, appName, hostname string) map[string]interface{} {
+	m := make(map[string]interface{})
+	m["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
+	m["@source_host"] = hostname
+	m["@message"] = entry.Message
+
+	fields := make(map[string]interface{})
+	fields["level"] = entry.Level.String()
+	fields["application"] = appName
+
+	for k, v := range entry.Data {
+		fields[k] = fieldValue(v)
+	}
+	m["@fields"] = fields
+
+	return m
+}
+
+func createLogstashV1Message(entry *logrus.Entry, appName, hostname string) map[string]interface{} {
+	m := make(map[string]interface{})
+	m["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
+	m["host"] = hostname
+	m["message"] = entry.Message
+	m["level"] = entry.Level.String()
+	m["application"] = appName
+	for k, v := range entry.Data {
+		m[k] = fieldValue(v)
+	}
+
+	return m
+}
+
+// fieldValue converts the error into string, otherwise errors are ignored by json encoding
+func fieldValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+package formatter
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogstashFormatter(t *testing.T) {
+	entry := &logrus.Entry{
+		Message: "hello",
+		Level:   logrus.DebugLevel,
+		Time:    time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC),
+		Data:    logrus.Fields{"a": 1, "error": errors.New("oops")},
+	}
+
+	// v0
+	f := &LogstashFormatter{Version: LogstashV0, Application: "app1", Hostname: "localhost"}
+	b, err := f.Format(entry)
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "2020-05-01T10:00:00Z", m["@timestamp"])
+	assert.Equal(t, "hello", m["@message"])
+	assert.Equal(t, "localhost", m["@source_host"])
+	assert.Equal(t, map[string]interface{}{
+		"level":       "debug",
+		"application": "app1",
+		"a":           float64(1),
+		"error":       "oops",
+	}, m["@fields"])
+
+	// v1, the default
+	f = &LogstashFormatter{Application: "app1", Hostname: "localhost"}
+	b, err = f.Format(entry)
+	assert.NoError(t, err)
+
+	m = nil
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, map[string]interface{}{
+		"@timestamp":  "2020-05-01T10:00:00Z",
+		"host":        "localhost",
+		"message":     "hello",
+		"level":       "debug",
+		"application": "app1",
+		"a":           float64(1),
+		"error":       "oops",
+	}, m)
+
+	// the hostname is empty if not set
+	f = &LogstashFormatter{}
+	b, err = f.Format(entry)
+	assert.NoError(t, err)
+
+	m = nil
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, "", m["host"])
+
+	// the hostname of machine
+	f = &LogstashFormatter{DefaultHostname: true}
+	b, err = f.Format(entry)
+	assert.NoError(t, err)
+
+	m = nil
+	assert.NoError(t, json.Unmarshal(b, &m))
+	assert.Equal(t, defaultHostname(), m["host"])
+}
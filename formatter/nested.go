@@ -0,0 +1,120 @@
+package formatter
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// the key of the leaf value which collides with the nested object, e.g.
+// `http=1` and `http.method=GET` will be `{"http": {"_value": 1, "method": "GET"}}`
+const nestedValueKey = "_value"
+
+// nestedFields is the object expanded from the dotted keys
+type nestedFields map[string]interface{}
+
+// expandFields expands the dotted keys into nested objects, `http.method` => `{"http": {"method": ...}}`.
+// the keys are processed in sorted order, and the leaf value collides with an object is kept as `_value` of the object,
+// so the result is deterministic. the keys with empty segment, e.g. `a..b` or `.a`, are not expanded
+func expandFields(data logrus.Fields) logrus.Fields {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make(logrus.Fields, len(data))
+	for _, k := range keys {
+		insertNested(out, splitDottedKey(k), data[k])
+	}
+	return out
+}
+
+func splitDottedKey(key string) []string {
+	path := strings.Split(key, ".")
+	for _, p := range path {
+		if p == "" {
+			return []string{key}
+		}
+	}
+	return path
+}
+
+func insertNested(m map[string]interface{}, path []string, value interface{}) {
+	for _, p := range path[:len(path)-1] {
+		child, exists := m[p]
+		obj, ok := child.(nestedFields)
+		if !ok {
+			obj = nestedFields{}
+			if exists {
+				obj[nestedValueKey] = child
+			}
+			m[p] = obj
+		}
+		m = obj
+	}
+
+	last := path[len(path)-1]
+	if obj, ok := m[last].(nestedFields); ok {
+		obj[nestedValueKey] = value
+		return
+	}
+	m[last] = value
+}
+
+// flattenFields flattens the nested map fields into dotted keys, `{"http": {"method": ...}}` => `http.method`.
+// the fields not flattened take precedence, then the flattened keys from the fields in sorted order
+func flattenFields(data logrus.Fields) logrus.Fields {
+	out := make(logrus.Fields, len(data))
+	var parents []string
+	for k, v := range data {
+		if isStringKeyMap(v) {
+			parents = append(parents, k)
+		} else {
+			out[k] = v
+		}
+	}
+	sort.Strings(parents)
+
+	for _, k := range parents {
+		flattenValue(out, k, reflect.ValueOf(data[k]))
+	}
+	return out
+}
+
+func flattenValue(out logrus.Fields, prefix string, v reflect.Value) {
+	if v.Len() == 0 {
+		setIfAbsent(out, prefix, v.Interface())
+		return
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, key := range keys {
+		name := prefix + "." + key.String()
+		child := v.MapIndex(key).Interface()
+		if isStringKeyMap(child) {
+			flattenValue(out, name, reflect.ValueOf(child))
+		} else {
+			setIfAbsent(out, name, child)
+		}
+	}
+}
+
+func setIfAbsent(data logrus.Fields, key string, value interface{}) {
+	if _, ok := data[key]; !ok {
+		data[key] = value
+	}
+}
+
+func isStringKeyMap(v interface{}) bool {
+	if v == nil {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	return t.Kind() == reflect.Map && t.Key().Kind() == reflect.String
+}
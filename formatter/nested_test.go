@@ -0,0 +1,116 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandFields(t *testing.T) {
+	tests := []struct {
+		name string
+		data logrus.Fields
+		want logrus.Fields
+	}{
+		{
+			name: "no dot",
+			data: logrus.Fields{"a": 1, "b": "c"},
+			want: logrus.Fields{"a": 1, "b": "c"},
+		},
+		{
+			name: "expand",
+			data: logrus.Fields{"http.method": "GET", "http.status": 200, "http.request.id": "abc", "a": 1},
+			want: logrus.Fields{
+				"a": 1,
+				"http": nestedFields{
+					"method":  "GET",
+					"status":  200,
+					"request": nestedFields{"id": "abc"},
+				},
+			},
+		},
+		{
+			name: "leaf collides with object",
+			data: logrus.Fields{"http": "x", "http.method": "GET", "a.b": 1, "a.b.c": 2},
+			want: logrus.Fields{
+				"http": nestedFields{nestedValueKey: "x", "method": "GET"},
+				"a":    nestedFields{"b": nestedFields{nestedValueKey: 1, "c": 2}},
+			},
+		},
+		{
+			name: "empty segment",
+			data: logrus.Fields{"a..b": 1, ".a": 2, "a.": 3},
+			want: logrus.Fields{"a..b": 1, ".a": 2, "a.": 3},
+		},
+		{
+			name: "map value is leaf",
+			data: logrus.Fields{"http": map[string]interface{}{"status": 200}, "http.method": "GET"},
+			want: logrus.Fields{
+				"http": nestedFields{nestedValueKey: map[string]interface{}{"status": 200}, "method": "GET"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the map iteration order is random, run it several times
+			for i := 0; i < 5; i++ {
+				assert.Equal(t, tt.want, expandFields(tt.data))
+			}
+		})
+	}
+}
+
+func TestFlattenFields(t *testing.T) {
+	tests := []struct {
+		name string
+		data logrus.Fields
+		want logrus.Fields
+	}{
+		{
+			name: "no map",
+			data: logrus.Fields{"a": 1, "b": []int{1}},
+			want: logrus.Fields{"a": 1, "b": []int{1}},
+		},
+		{
+			name: "flatten",
+			data: logrus.Fields{
+				"http": map[string]interface{}{
+					"method":  "GET",
+					"request": logrus.Fields{"id": "abc"},
+				},
+				"labels": map[string]string{"env": "prod"},
+				"empty":  map[string]int{},
+			},
+			want: logrus.Fields{
+				"http.method":     "GET",
+				"http.request.id": "abc",
+				"labels.env":      "prod",
+				"empty":           map[string]int{},
+			},
+		},
+		{
+			name: "collision",
+			data: logrus.Fields{
+				"http.method": "POST",
+				"http":        map[string]interface{}{"method": "GET", "status": 200},
+				"a":           map[string]interface{}{"b.c": 1},
+				"a.b":         map[string]interface{}{"c": 2},
+			},
+			want: logrus.Fields{
+				"http.method": "POST",
+				"http.status": 200,
+				"a.b.c":       1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 5; i++ {
+				assert.Equal(t, tt.want, flattenFields(tt.data))
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package formatter
+
+import (
+	"strconv"
+	"unicode"
+)
+
+// quoteIfNeeded quotes the value if it's empty or contains space, `=`, `"` or non-printable characters,
+// shared by the console and logfmt formatters; the non-printable characters, e.g. `\u00a0` and `\u200b`,
+// are quoted so the value can't be mistaken for another key or hidden from the reader
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || !unicode.IsPrint(c) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
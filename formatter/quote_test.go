@@ -0,0 +1,29 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuoteIfNeeded(t *testing.T) {
+	var data = []struct {
+		value    string
+		expected string
+	}{
+		{"", `""`},
+		{"abc", "abc"},
+		{"中文", "中文"},
+		{"a b", `"a b"`},
+		{"a=b", `"a=b"`},
+		{`a"b`, `"a\"b"`},
+		{"a\nb", `"a\nb"`},
+		{"a\x7fb", `"a\x7fb"`},
+		// the non-printable characters
+		{"a\u00a0b", `"a\u00a0b"`},
+		{"a\u200bb", `"a\u200bb"`},
+	}
+	for _, d := range data {
+		assert.Equal(t, d.expected, quoteIfNeeded(d.value), d.value)
+	}
+}
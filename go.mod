@@ -3,21 +3,30 @@ module github.com/wklken/logging-go
 go 1.14
 
 require (
+	github.com/Shopify/sarama v1.27.2
 	github.com/certifi/gocertifi v0.0.0-20200211180108-c7c1fbc02894 // indirect
 	github.com/evalphobia/logrus_sentry v0.8.2
 	github.com/fastly/go-utils v0.0.0-20180712184237-d95a45783239 // indirect
 	github.com/getsentry/raven-go v0.2.0 // indirect
 	github.com/go-redis/redis v6.15.7+incompatible
+	github.com/golang/snappy v0.0.1
 	github.com/jehiah/go-strftime v0.0.0-20171201141054-1d33003b3869 // indirect
 	github.com/jonboulle/clockwork v0.1.0 // indirect
 	github.com/json-iterator/go v1.1.9
 	github.com/lestrrat-go/file-rotatelogs v2.3.0+incompatible
-	github.com/lestrrat-go/strftime v1.0.1 // indirect
+	github.com/lestrrat-go/strftime v1.0.1
+	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/onsi/ginkgo v1.12.0 // indirect
 	github.com/onsi/gomega v1.9.0 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/rifflock/lfshook v0.0.0-20180920164130-b9218ef580f5
 	github.com/sirupsen/logrus v1.5.0
-	github.com/stretchr/testify v1.5.1
+	github.com/stretchr/testify v1.7.1
 	github.com/tebeka/strftime v0.1.4 // indirect
+	github.com/tinylib/msgp v1.1.6
+	github.com/xdg/scram v0.0.0-20180814205039-7eeb5667e42c
+	go.opentelemetry.io/otel/trace v1.7.0
+	go.opentelemetry.io/proto/otlp v0.19.0
+	google.golang.org/grpc v1.42.0
+	google.golang.org/protobuf v1.27.1
 )
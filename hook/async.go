@@ -0,0 +1,98 @@
+package hook
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dropReportInterval is the min interval of the messages about the dropped entries
+const dropReportInterval = 10 * time.Second
+
+// dropCounter counts the entries dropped when the buffered chan is full,
+// and prints the total to stderr at most once per dropReportInterval
+type dropCounter struct {
+	// dropped is the first field for the 64-bit atomic alignment on 32-bit platforms
+	dropped    uint64
+	lastReport int64
+}
+
+// drop counts one dropped entry, and prints the total if not printed recently
+func (c *dropCounter) drop(name string) {
+	dropped := atomic.AddUint64(&c.dropped, 1)
+
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&c.lastReport)
+	if now-last < int64(dropReportInterval) || !atomic.CompareAndSwapInt64(&c.lastReport, last, now) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "the log buffered chan of %s hook is full! %d entries dropped\n", name, dropped)
+}
+
+// Dropped returns the number of the entries dropped
+func (c *dropCounter) Dropped() uint64 {
+	return atomic.LoadUint64(&c.dropped)
+}
+
+// asyncSender is the buffered chan + worker used by the hooks in async mode,
+// it's the same as FileLogHook/RedisLogHook do, but shared by the newer hooks
+type asyncSender struct {
+	dropCounter
+
+	name        string
+	fireChannel chan *logrus.Entry
+	block       bool
+
+	send func(entry *logrus.Entry) error
+}
+
+func newAsyncSender(name string, bufferSize int, block bool, send func(entry *logrus.Entry) error) *asyncSender {
+	a := &asyncSender{
+		name:        name,
+		fireChannel: make(chan *logrus.Entry, bufferSize),
+		block:       block,
+		send:        send,
+	}
+	fmt.Printf("%s hook will use a async buffer with size %d\n", name, bufferSize)
+
+	go func() {
+		for entry := range a.fireChannel {
+			if err := a.send(entry); err != nil {
+				fmt.Printf("Error during sending message to %s: %s\n", a.name, err)
+			}
+		}
+	}()
+	return a
+}
+
+// Fire put the entry into the buffered chan, drop or block when the chan is full
+func (a *asyncSender) Fire(entry *logrus.Entry) error {
+	if !putEntry(a.fireChannel, entry, a.block) {
+		a.drop(a.name)
+	}
+	return nil
+}
+
+// putEntry put a copy of the entry into the chan, drop or block when the chan is full,
+// returns false if the entry is dropped
+func putEntry(ch chan *logrus.Entry, entry *logrus.Entry, block bool) bool {
+	// logrus will set a pooled Buffer into the entry after the hooks fired,
+	// send a copy, so the formatter in the worker goroutine will not write into it
+	e := *entry
+	e.Buffer = nil
+
+	select {
+	case ch <- &e:
+	default:
+		if block {
+			ch <- &e // Blocks the goroutine because buffer is full.
+			return true
+		}
+		// Drop message by default.
+		return false
+	}
+	return true
+}
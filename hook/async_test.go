@@ -0,0 +1,66 @@
+package hook
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncSender(t *testing.T) {
+	received := make(chan string, 10)
+	a := newAsyncSender("test", 10, false, func(entry *logrus.Entry) error {
+		received <- entry.Message
+		return nil
+	})
+
+	err := a.Fire(&logrus.Entry{Message: "hello"})
+	assert.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, "hello", msg)
+	case <-time.After(time.Second):
+		t.Fatal("entry not sent")
+	}
+}
+
+func TestAsyncSenderDrop(t *testing.T) {
+	wait := make(chan struct{})
+	a := newAsyncSender("test", 1, false, func(entry *logrus.Entry) error {
+		<-wait
+		return nil
+	})
+	defer close(wait)
+
+	// the worker holds one, the buffer holds one, the others should be dropped without blocking
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, a.Fire(&logrus.Entry{Message: "hello"}))
+	}
+	// the worker may not take the first one yet
+	assert.True(t, a.Dropped() >= 8)
+}
+
+func TestDropCounter(t *testing.T) {
+	stderr := os.Stderr
+	defer func() { os.Stderr = stderr }()
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stderr = w
+
+	var c dropCounter
+	for i := 0; i < 10; i++ {
+		c.drop("test")
+	}
+	assert.NoError(t, w.Close())
+	assert.Equal(t, uint64(10), c.Dropped())
+
+	// only the first one is reported in the interval
+	b, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "the log buffered chan of test hook is full! 1 entries dropped\n", string(b))
+}
@@ -0,0 +1,198 @@
+package hook
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultBatchMaxCount      = 100
+	defaultBatchMaxBytes      = 1024 * 1024
+	defaultBatchFlushInterval = time.Second
+)
+
+// batchItem is an entry with the encoded data, the len of data is counted into the batch bytes;
+// or with the converted value and its size, e.g. the LogRecord of otlp, see newValueBatcher
+type batchItem struct {
+	entry *logrus.Entry
+	data  []byte
+	value interface{}
+	size  int
+}
+
+// batchConfig stores the batch settings: batch_max_count, batch_max_bytes, flush_interval
+type batchConfig struct {
+	maxCount      int
+	maxBytes      int
+	flushInterval time.Duration
+}
+
+func getBatchSettings(settings map[string]string) (batchConfig, error) {
+	var config batchConfig
+	var err error
+
+	if config.maxCount, err = getIntSetting(settings, "batch_max_count", defaultBatchMaxCount); err != nil {
+		return config, err
+	}
+	if config.maxBytes, err = getIntSetting(settings, "batch_max_bytes", defaultBatchMaxBytes); err != nil {
+		return config, err
+	}
+	if config.flushInterval, err = getDurationSetting(settings, "flush_interval", defaultBatchFlushInterval); err != nil {
+		return config, err
+	}
+	if config.maxCount <= 0 || config.maxBytes <= 0 || config.flushInterval <= 0 {
+		return config, fmt.Errorf("batch_max_count, batch_max_bytes and flush_interval should be positive")
+	}
+	return config, nil
+}
+
+// batcher collects the entries in a worker goroutine, and flush them when reach the max count/bytes
+// or every flush interval. In sync mode, each entry will be flushed as a batch of one.
+type batcher struct {
+	dropCounter
+
+	name   string
+	config batchConfig
+
+	encode  func(entry *logrus.Entry) ([]byte, error)
+	convert func(entry *logrus.Entry) (value interface{}, size int, err error)
+	flush   func(items []batchItem) error
+
+	// async mode
+	fireChannel  chan *logrus.Entry
+	flushChannel chan chan struct{}
+	block        bool
+
+	pending      []batchItem
+	pendingBytes int
+}
+
+func newBatcher(name string, config batchConfig,
+	encode func(entry *logrus.Entry) ([]byte, error), flush func(items []batchItem) error,
+	asyncEnable bool, asyncBufferSize int, asyncBlock bool) *batcher {
+	b := &batcher{
+		name:   name,
+		config: config,
+		encode: encode,
+		flush:  flush,
+	}
+	b.start(asyncEnable, asyncBufferSize, asyncBlock)
+	return b
+}
+
+// newValueBatcher returns the batcher keeps the values converted from the entries instead of the encoded data,
+// for the flush builds the request from the values, e.g. the proto messages
+func newValueBatcher(name string, config batchConfig,
+	convert func(entry *logrus.Entry) (interface{}, int, error), flush func(items []batchItem) error,
+	asyncEnable bool, asyncBufferSize int, asyncBlock bool) *batcher {
+	b := &batcher{
+		name:    name,
+		config:  config,
+		convert: convert,
+		flush:   flush,
+	}
+	b.start(asyncEnable, asyncBufferSize, asyncBlock)
+	return b
+}
+
+func (b *batcher) start(asyncEnable bool, asyncBufferSize int, asyncBlock bool) {
+	if asyncEnable {
+		b.fireChannel = make(chan *logrus.Entry, asyncBufferSize)
+		b.flushChannel = make(chan chan struct{})
+		b.block = asyncBlock
+		fmt.Printf("%s hook will use a async buffer with size %d, batch max count %d, flush interval %s\n",
+			b.name, asyncBufferSize, b.config.maxCount, b.config.flushInterval)
+		go b.run()
+	}
+}
+
+// item encodes or converts the entry
+func (b *batcher) item(entry *logrus.Entry) (batchItem, error) {
+	if b.convert != nil {
+		value, size, err := b.convert(entry)
+		return batchItem{entry: entry, value: value, size: size}, err
+	}
+	data, err := b.encode(entry)
+	return batchItem{entry: entry, data: data, size: len(data)}, err
+}
+
+// Fire is called when a log event is fired.
+func (b *batcher) Fire(entry *logrus.Entry) error {
+	if b.fireChannel != nil { // Async mode.
+		if !putEntry(b.fireChannel, entry, b.block) {
+			b.drop(b.name)
+		}
+		return nil
+	}
+
+	item, err := b.item(entry)
+	if err != nil {
+		return err
+	}
+	return b.flush([]batchItem{item})
+}
+
+// Flush sends all the pending entries, and wait until done
+func (b *batcher) Flush() {
+	if b.flushChannel == nil {
+		return
+	}
+	done := make(chan struct{})
+	b.flushChannel <- done
+	<-done
+}
+
+func (b *batcher) run() {
+	ticker := time.NewTicker(b.config.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case entry := <-b.fireChannel:
+			b.add(entry)
+		case <-ticker.C:
+			b.flushPending()
+		case done := <-b.flushChannel:
+			// drain the buffered entries first
+			for n := len(b.fireChannel); n > 0; n-- {
+				b.add(<-b.fireChannel)
+			}
+			b.flushPending()
+			close(done)
+		}
+	}
+}
+
+func (b *batcher) add(entry *logrus.Entry) {
+	item, err := b.item(entry)
+	if err != nil {
+		fmt.Printf("Error during encoding message for %s: %s\n", b.name, err)
+		return
+	}
+
+	if len(b.pending) > 0 && b.pendingBytes+item.size > b.config.maxBytes {
+		b.flushPending()
+	}
+
+	b.pending = append(b.pending, item)
+	b.pendingBytes += item.size
+
+	if len(b.pending) >= b.config.maxCount || b.pendingBytes >= b.config.maxBytes {
+		b.flushPending()
+	}
+}
+
+func (b *batcher) flushPending() {
+	if len(b.pending) == 0 {
+		return
+	}
+
+	if err := b.flush(b.pending); err != nil {
+		fmt.Printf("Error during sending %d messages to %s: %s\n", len(b.pending), b.name, err)
+	}
+
+	b.pending = nil
+	b.pendingBytes = 0
+}
@@ -0,0 +1,99 @@
+package hook
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBatchSettings(t *testing.T) {
+	config, err := getBatchSettings(map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultBatchMaxCount, config.maxCount)
+	assert.Equal(t, defaultBatchMaxBytes, config.maxBytes)
+	assert.Equal(t, defaultBatchFlushInterval, config.flushInterval)
+
+	config, err = getBatchSettings(map[string]string{"batch_max_count": "10", "batch_max_bytes": "1024", "flush_interval": "5s"})
+	assert.NoError(t, err)
+	assert.Equal(t, batchConfig{maxCount: 10, maxBytes: 1024, flushInterval: 5 * time.Second}, config)
+
+	var data = []map[string]string{
+		{"batch_max_count": "a"},
+		{"batch_max_bytes": "a"},
+		{"flush_interval": "a"},
+		{"batch_max_count": "0"},
+	}
+	for _, d := range data {
+		_, err := getBatchSettings(d)
+		assert.Error(t, err)
+	}
+}
+
+type testBatchRecorder struct {
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (r *testBatchRecorder) encode(entry *logrus.Entry) ([]byte, error) {
+	return []byte(entry.Message), nil
+}
+
+func (r *testBatchRecorder) flush(items []batchItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch := make([]string, 0, len(items))
+	for _, item := range items {
+		batch = append(batch, string(item.data))
+	}
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func (r *testBatchRecorder) get() [][]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.batches
+}
+
+func TestBatcherSync(t *testing.T) {
+	r := &testBatchRecorder{}
+	b := newBatcher("test", batchConfig{maxCount: 10, maxBytes: 100, flushInterval: time.Hour},
+		r.encode, r.flush, false, 0, false)
+
+	assert.NoError(t, b.Fire(&logrus.Entry{Message: "a"}))
+	assert.NoError(t, b.Fire(&logrus.Entry{Message: "b"}))
+	// flush do nothing in sync mode
+	b.Flush()
+
+	assert.Equal(t, [][]string{{"a"}, {"b"}}, r.get())
+}
+
+func TestBatcherMaxCountAndBytes(t *testing.T) {
+	r := &testBatchRecorder{}
+	b := newBatcher("test", batchConfig{maxCount: 2, maxBytes: 5, flushInterval: time.Hour},
+		r.encode, r.flush, true, 100, true)
+
+	for _, msg := range []string{"a", "b", "c", "dddd", "eeeeee", "f"} {
+		assert.NoError(t, b.Fire(&logrus.Entry{Message: msg}))
+	}
+	b.Flush()
+
+	// max count 2: [a b]; max bytes 5: [c dddd]; too large to fit with others: [eeeeee]; flush: [f]
+	assert.Equal(t, [][]string{{"a", "b"}, {"c", "dddd"}, {"eeeeee"}, {"f"}}, r.get())
+}
+
+func TestBatcherFlushInterval(t *testing.T) {
+	r := &testBatchRecorder{}
+	b := newBatcher("test", batchConfig{maxCount: 100, maxBytes: 100, flushInterval: 10 * time.Millisecond},
+		r.encode, r.flush, true, 100, true)
+
+	assert.NoError(t, b.Fire(&logrus.Entry{Message: "a"}))
+
+	assert.Eventually(t, func() bool {
+		return len(r.get()) == 1
+	}, time.Second, 10*time.Millisecond)
+}
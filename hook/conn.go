@@ -0,0 +1,240 @@
+package hook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FramingOctetCounting is the framing of rfc6587, `MSG-LEN SP MSG`
+	FramingOctetCounting = "octet_counting"
+	// FramingNewline is the non-transparent framing, each message ends with a `\n`
+	FramingNewline = "newline"
+	// FramingLengthPrefixed prefix each message with a 4 bytes big-endian length
+	FramingLengthPrefixed = "length_prefixed"
+
+	defaultDialTimeout     = 5 * time.Second
+	defaultWriteTimeout    = 5 * time.Second
+	defaultMaxRetries      = 1
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultMaxRetryBackoff = 5 * time.Second
+)
+
+// netWriter is a writer over tcp/udp/unix/tls, it dials lazily and reconnects if the write fails
+type netWriter struct {
+	network      string
+	address      string
+	tlsConfig    *tls.Config
+	dialTimeout  time.Duration
+	writeTimeout time.Duration
+
+	// maxRetries is the times to reconnect and write again after the first write fails,
+	// wait retryBackoff before the first retry, then double it each time, up to maxRetryBackoff
+	maxRetries      int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newNetWriter(network, address string, tlsConfig *tls.Config, dialTimeout, writeTimeout time.Duration) *netWriter {
+	return &netWriter{
+		network:         network,
+		address:         address,
+		tlsConfig:       tlsConfig,
+		dialTimeout:     dialTimeout,
+		writeTimeout:    writeTimeout,
+		maxRetries:      defaultMaxRetries,
+		retryBackoff:    defaultRetryBackoff,
+		maxRetryBackoff: defaultMaxRetryBackoff,
+	}
+}
+
+// newNetWriterWithSettings build the netWriter from the common settings:
+// dial_timeout, write_timeout, max_retries, retry_backoff, max_retry_backoff, and tls_* if useTLS
+func newNetWriterWithSettings(network, address string, useTLS bool, settings map[string]string) (*netWriter, error) {
+	dialTimeout, err := getDurationSetting(settings, "dial_timeout", defaultDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	writeTimeout, err := getDurationSetting(settings, "write_timeout", defaultWriteTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var tlsConfig *tls.Config
+	if useTLS {
+		tlsConfig, err = getTLSConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	w := newNetWriter(network, address, tlsConfig, dialTimeout, writeTimeout)
+
+	if w.maxRetries, err = getIntSetting(settings, "max_retries", defaultMaxRetries); err != nil {
+		return nil, err
+	}
+	if w.retryBackoff, err = getDurationSetting(settings, "retry_backoff", defaultRetryBackoff); err != nil {
+		return nil, err
+	}
+	if w.maxRetryBackoff, err = getDurationSetting(settings, "max_retry_backoff", defaultMaxRetryBackoff); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// isStream returns true if the transport is connection oriented, the message should be framed
+func (w *netWriter) isStream() bool {
+	switch w.network {
+	case "udp", "udp4", "udp6", "unixgram":
+		return false
+	default:
+		return true
+	}
+}
+
+func (w *netWriter) connect() error {
+	if w.conn != nil {
+		return nil
+	}
+
+	dialer := &net.Dialer{Timeout: w.dialTimeout}
+
+	var conn net.Conn
+	var err error
+	if w.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, w.network, w.address, w.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(w.network, w.address)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "dial %s %s fail", w.network, w.address)
+	}
+
+	w.conn = conn
+	return nil
+}
+
+// Write writes the whole p to the connection, reconnect and retry with backoff if the write fails
+func (w *netWriter) Write(p []byte) (n int, err error) {
+	if err = w.writeAndRead(p, nil); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// writeAndRead writes p, then read the response(e.g. the ack) from the same connection if read is not nil,
+// reconnect and retry with backoff if the write or read fails
+func (w *netWriter) writeAndRead(p []byte, read func(conn net.Conn) error) (err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	backoff := w.retryBackoff
+	for i := 0; i <= w.maxRetries; i++ {
+		if i > 0 && backoff > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > w.maxRetryBackoff {
+				backoff = w.maxRetryBackoff
+			}
+		}
+
+		if err = w.connect(); err != nil {
+			continue
+		}
+
+		if w.writeTimeout > 0 {
+			_ = w.conn.SetWriteDeadline(time.Now().Add(w.writeTimeout))
+		}
+		_, err = w.conn.Write(p)
+		if err == nil && read != nil {
+			err = read(w.conn)
+		}
+		if err == nil {
+			return nil
+		}
+
+		// the connection is broken, close it and dial again
+		_ = w.conn.Close()
+		w.conn = nil
+	}
+	return err
+}
+
+// Close closes the underlying connection, the next Write will dial again
+func (w *netWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// frame wraps the message with the framing, the trailing newline of message will be trimmed
+func frame(framing string, msg []byte) []byte {
+	if n := len(msg); n > 0 && msg[n-1] == '\n' {
+		msg = msg[:n-1]
+	}
+
+	switch framing {
+	case FramingOctetCounting:
+		return append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	case FramingLengthPrefixed:
+		b := make([]byte, 4, 4+len(msg))
+		binary.BigEndian.PutUint32(b, uint32(len(msg)))
+		return append(b, msg...)
+	default:
+		return append(msg, '\n')
+	}
+}
+
+// getTLSConfig build the tls config from settings:
+// tls_ca_file, tls_cert_file, tls_key_file, tls_server_name, tls_insecure_skip_verify
+func getTLSConfig(settings map[string]string) (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName:         settings["tls_server_name"],
+		InsecureSkipVerify: getBoolSetting(settings, "tls_insecure_skip_verify", false), // nolint:gosec
+	}
+
+	if caFile, ok := settings["tls_ca_file"]; ok {
+		ca, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "read tls_ca_file %s fail", caFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("tls_ca_file %s contains no valid certificate", caFile)
+		}
+		config.RootCAs = pool
+	}
+
+	certFile, hasCert := settings["tls_cert_file"]
+	keyFile, hasKey := settings["tls_key_file"]
+	if hasCert != hasKey {
+		return nil, errors.New("tls_cert_file and tls_key_file should be set together")
+	}
+	if hasCert {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "load tls_cert_file/tls_key_file fail")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
@@ -0,0 +1,188 @@
+package hook
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestTLSFiles generate a self-signed certificate for 127.0.0.1, returns the cert and key file path
+func newTestTLSFiles(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "logging-tls")
+	assert.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func TestGetTLSConfig(t *testing.T) {
+	certFile, keyFile := newTestTLSFiles(t)
+
+	config, err := getTLSConfig(map[string]string{})
+	assert.NoError(t, err)
+	assert.Nil(t, config.RootCAs)
+	assert.False(t, config.InsecureSkipVerify)
+
+	config, err = getTLSConfig(map[string]string{
+		"tls_ca_file":              certFile,
+		"tls_cert_file":            certFile,
+		"tls_key_file":             keyFile,
+		"tls_server_name":          "example.com",
+		"tls_insecure_skip_verify": "true",
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+	assert.Equal(t, "example.com", config.ServerName)
+	assert.True(t, config.InsecureSkipVerify)
+
+	var data = []map[string]string{
+		{"tls_ca_file": "/not/exists"},
+		{"tls_ca_file": keyFile},
+		{"tls_cert_file": certFile},
+		{"tls_cert_file": certFile, "tls_key_file": certFile},
+	}
+	for _, d := range data {
+		_, err := getTLSConfig(d)
+		assert.Error(t, err)
+	}
+}
+
+func TestNetWriterReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					lines <- scanner.Text()
+				}
+			}(conn)
+		}
+	}()
+
+	w := newNetWriter("tcp", ln.Addr().String(), nil, time.Second, time.Second)
+	assert.True(t, w.isStream())
+
+	_, err = w.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", <-lines)
+
+	// close the connection, will dial a new one
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("world\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "world", <-lines)
+
+	assert.False(t, newNetWriter("udp", "127.0.0.1:514", nil, time.Second, time.Second).isStream())
+}
+
+func TestNetWriterDialFail(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	w := newNetWriter("tcp", addr, nil, time.Second, time.Second)
+	_, err = w.Write([]byte("hello\n"))
+	assert.Error(t, err)
+}
+
+func TestNetWriterTLS(t *testing.T) {
+	certFile, keyFile := newTestTLSFiles(t)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		scanner := bufio.NewScanner(conn)
+		if scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	tlsConfig, err := getTLSConfig(map[string]string{"tls_ca_file": certFile})
+	assert.NoError(t, err)
+
+	w := newNetWriter("tcp", ln.Addr().String(), tlsConfig, time.Second, time.Second)
+	defer w.Close()
+	_, err = w.Write([]byte("hello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", <-lines)
+}
+
+func TestNewNetWriterWithSettings(t *testing.T) {
+	w, err := newNetWriterWithSettings("tcp", "127.0.0.1:5170", false, map[string]string{})
+	assert.NoError(t, err)
+	assert.Nil(t, w.tlsConfig)
+	assert.Equal(t, defaultMaxRetries, w.maxRetries)
+	assert.Equal(t, defaultWriteTimeout, w.writeTimeout)
+
+	w, err = newNetWriterWithSettings("tcp", "127.0.0.1:5170", true, map[string]string{
+		"dial_timeout":      "1s",
+		"write_timeout":     "2s",
+		"max_retries":       "3",
+		"retry_backoff":     "10ms",
+		"max_retry_backoff": "1s",
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, w.tlsConfig)
+	assert.Equal(t, time.Second, w.dialTimeout)
+	assert.Equal(t, 2*time.Second, w.writeTimeout)
+	assert.Equal(t, 3, w.maxRetries)
+	assert.Equal(t, 10*time.Millisecond, w.retryBackoff)
+	assert.Equal(t, time.Second, w.maxRetryBackoff)
+}
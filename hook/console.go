@@ -0,0 +1,109 @@
+package hook
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/wklken/logging-go/formatter"
+)
+
+type ConsoleLogHookBuilder struct {
+}
+
+// console: print the entries to stdout/stderr, with its own level, format and color
+func (b ConsoleLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	var writer io.Writer
+	switch settings["writer"] {
+	case "stdout":
+		writer = os.Stdout
+	case "stderr", "":
+		writer = os.Stderr
+	default:
+		return nil, fmt.Errorf("unsupported console writer %s, should be stdout or stderr", settings["writer"])
+	}
+
+	level := logrus.TraceLevel
+	if levelStr, ok := settings["level"]; ok {
+		var err error
+		if level, err = logrus.ParseLevel(levelStr); err != nil {
+			return nil, err
+		}
+	}
+
+	color, err := getColorSetting(settings, writer)
+	if err != nil {
+		return nil, err
+	}
+
+	var f logrus.Formatter
+	switch settings["format"] {
+	case "text", "":
+		f = &logrus.TextFormatter{ForceColors: color, DisableColors: !color}
+	case "console":
+		f = &formatter.ConsoleFormatter{ForceColors: color, DisableColors: !color}
+	case "logfmt":
+		f = &formatter.LogfmtFormatter{}
+	case "json":
+		f = &formatter.JSONFormatter{}
+	default:
+		return nil, fmt.Errorf("unsupported console format %s, should be text, console, logfmt or json", settings["format"])
+	}
+
+	return newConsoleHook(writer, level, f), nil
+}
+
+// ConsoleLogHook writes the formatted entries to the writer, for the entries at or above the level
+type ConsoleLogHook struct {
+	writer    io.Writer
+	level     logrus.Level
+	formatter logrus.Formatter
+
+	mu sync.Mutex
+}
+
+func newConsoleHook(writer io.Writer, level logrus.Level, formatter logrus.Formatter) *ConsoleLogHook {
+	return &ConsoleLogHook{
+		writer:    writer,
+		level:     level,
+		formatter: formatter,
+	}
+}
+
+// Fire is called when a log event is fired.
+func (h *ConsoleLogHook) Fire(entry *logrus.Entry) error {
+	msg, err := h.formatter.Format(entry)
+	if err != nil {
+		return fmt.Errorf("error creating message for CONSOLE: %s", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, err := h.writer.Write(msg); err != nil {
+		return fmt.Errorf("error writing message to CONSOLE: %s", err)
+	}
+	return nil
+}
+
+// Levels returns the levels at or above the level.
+func (h *ConsoleLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels[:h.level+1]
+}
+
+// getColorSetting parse the color setting, `auto`(default) enables color if the writer is a terminal
+func getColorSetting(settings map[string]string, writer io.Writer) (bool, error) {
+	switch settings["color"] {
+	case "auto", "":
+		return formatter.IsTerminal(writer), nil
+	case "true", "1":
+		return true, nil
+	case "false", "0":
+		return false, nil
+	default:
+		return false, fmt.Errorf("unsupported color %s, should be auto, true or false", settings["color"])
+	}
+}
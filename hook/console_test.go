@@ -0,0 +1,103 @@
+package hook
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/wklken/logging-go/formatter"
+)
+
+func TestNewConsoleHook(t *testing.T) {
+	f := ConsoleLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// normal
+		{map[string]string{}, false},
+		{map[string]string{"writer": "stdout", "level": "info", "format": "json", "color": "false"}, false},
+		{map[string]string{"writer": "stderr", "format": "text", "color": "true"}, false},
+		{map[string]string{"format": "console"}, false},
+		// wrong writer, level, format, color
+		{map[string]string{"writer": "file"}, true},
+		{map[string]string{"level": "unknown"}, true},
+		{map[string]string{"format": "xml"}, true},
+		{map[string]string{"color": "yes"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestConsoleLogHookLevels(t *testing.T) {
+	var data = []struct {
+		level    logrus.Level
+		expected []logrus.Level
+	}{
+		{logrus.TraceLevel, logrus.AllLevels},
+		{logrus.WarnLevel, []logrus.Level{logrus.PanicLevel, logrus.FatalLevel, logrus.ErrorLevel, logrus.WarnLevel}},
+		{logrus.PanicLevel, []logrus.Level{logrus.PanicLevel}},
+	}
+	for _, d := range data {
+		h := newConsoleHook(os.Stdout, d.level, &formatter.NullFormatter{})
+		assert.Equal(t, d.expected, h.Levels())
+	}
+}
+
+func TestConsoleLogHookFire(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := logrus.New()
+	logger.SetOutput(&bytes.Buffer{})
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(newConsoleHook(&buf, logrus.InfoLevel, &formatter.JSONFormatter{}))
+
+	logger.Debug("debug")
+	assert.Equal(t, 0, buf.Len())
+
+	logger.WithField("id", 1).Info("hello")
+
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "hello", line["msg"])
+	assert.Equal(t, float64(1), line["id"])
+}
+
+func TestConsoleLogHookColor(t *testing.T) {
+	var buf bytes.Buffer
+
+	color, err := getColorSetting(map[string]string{}, &buf)
+	assert.NoError(t, err)
+	assert.False(t, color)
+
+	color, err = getColorSetting(map[string]string{"color": "true"}, &buf)
+	assert.NoError(t, err)
+	assert.True(t, color)
+
+	h, err := ConsoleLogHookBuilder{}.New("console", map[string]string{"color": "true"})
+	assert.NoError(t, err)
+	h.(*ConsoleLogHook).writer = &buf
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Level: logrus.ErrorLevel, Data: logrus.Fields{}}))
+	assert.Contains(t, buf.String(), "\x1b[31m")
+
+	buf.Reset()
+	h, err = ConsoleLogHookBuilder{}.New("console", map[string]string{"color": "false"})
+	assert.NoError(t, err)
+	h.(*ConsoleLogHook).writer = &buf
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Level: logrus.ErrorLevel, Data: logrus.Fields{}}))
+	assert.NotContains(t, buf.String(), "\x1b[")
+	assert.Contains(t, buf.String(), "msg=hello")
+}
@@ -0,0 +1,124 @@
+package hook
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// FieldKeyOccurrences is the number of the repeated entries in the dedup window, added to the entry sent when the window
+// closes; the first entry sent immediately is not counted, so the total in the window is occurrences + 1
+const FieldKeyOccurrences = "occurrences"
+
+// WithDedup wraps the hook with the dedup window if the setting `dedup_window` is set, e.g. `1m`,
+// the hook itself is returned if not set or 0
+func WithDedup(hook logrus.Hook, name string, settings map[string]string) (logrus.Hook, error) {
+	window, err := getDurationSetting(settings, "dedup_window", 0)
+	if err != nil {
+		return nil, err
+	}
+	if window <= 0 {
+		return hook, nil
+	}
+	return newDedupHook(hook, name, window), nil
+}
+
+// DedupLogHook suppresses the duplicate entries, keyed by level, message, error type and caller:
+// the first entry is sent, the repeats in the window are counted instead of sent,
+// then the last repeat is sent with the field `occurrences` when the window closes
+type DedupLogHook struct {
+	hook   logrus.Hook
+	name   string
+	window time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*dedupWindow
+
+	// fireMu serializes the fire of the wrapped hook, the entries sent by the timer are not serialized by the logger
+	fireMu sync.Mutex
+}
+
+type dedupWindow struct {
+	count int
+	last  *logrus.Entry
+}
+
+func newDedupHook(hook logrus.Hook, name string, window time.Duration) *DedupLogHook {
+	return &DedupLogHook{
+		hook:    hook,
+		name:    name,
+		window:  window,
+		windows: map[string]*dedupWindow{},
+	}
+}
+
+// Fire is called when a log event is fired.
+func (h *DedupLogHook) Fire(entry *logrus.Entry) error {
+	key := dedupKey(entry)
+
+	h.mu.Lock()
+	if w, ok := h.windows[key]; ok {
+		w.count++
+		w.last = copyDedupEntry(entry)
+		h.mu.Unlock()
+		return nil
+	}
+	h.windows[key] = &dedupWindow{}
+	h.mu.Unlock()
+
+	time.AfterFunc(h.window, func() {
+		if err := h.flush(key); err != nil {
+			fmt.Printf("Error during sending message to %s: %s\n", h.name, err)
+		}
+	})
+	return h.fire(entry)
+}
+
+func (h *DedupLogHook) fire(entry *logrus.Entry) error {
+	h.fireMu.Lock()
+	defer h.fireMu.Unlock()
+	return h.hook.Fire(entry)
+}
+
+// Levels returns the available logging levels.
+func (h *DedupLogHook) Levels() []logrus.Level {
+	return h.hook.Levels()
+}
+
+// flush closes the window, sends the last repeat with the occurrences if any
+func (h *DedupLogHook) flush(key string) error {
+	h.mu.Lock()
+	w := h.windows[key]
+	delete(h.windows, key)
+	h.mu.Unlock()
+
+	if w == nil || w.count == 0 {
+		return nil
+	}
+	w.last.Data[FieldKeyOccurrences] = w.count
+	return h.fire(w.last)
+}
+
+func dedupKey(entry *logrus.Entry) string {
+	key := entry.Level.String() + "|" + entry.Message
+	if err, ok := entry.Data[logrus.ErrorKey]; ok {
+		key += fmt.Sprintf("|%T", err)
+	}
+	if entry.HasCaller() {
+		key += fmt.Sprintf("|%s|%s:%d", entry.Caller.Function, entry.Caller.File, entry.Caller.Line)
+	}
+	return key
+}
+
+// copyDedupEntry copies the entry to be sent later, the data is copied for the occurrences
+func copyDedupEntry(entry *logrus.Entry) *logrus.Entry {
+	e := *entry
+	e.Buffer = nil
+	e.Data = make(logrus.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		e.Data[k] = v
+	}
+	return &e
+}
@@ -0,0 +1,145 @@
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testDedupRecorder struct {
+	mu      sync.Mutex
+	entries []*logrus.Entry
+}
+
+func (r *testDedupRecorder) Fire(entry *logrus.Entry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, entry)
+	return nil
+}
+
+func (r *testDedupRecorder) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel}
+}
+
+func (r *testDedupRecorder) get() []*logrus.Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*logrus.Entry(nil), r.entries...)
+}
+
+func TestWithDedup(t *testing.T) {
+	recorder := &testDedupRecorder{}
+
+	h, err := WithDedup(recorder, "test", map[string]string{})
+	assert.NoError(t, err)
+	assert.Equal(t, recorder, h)
+
+	h, err = WithDedup(recorder, "test", map[string]string{"dedup_window": "0s"})
+	assert.NoError(t, err)
+	assert.Equal(t, recorder, h)
+
+	_, err = WithDedup(recorder, "test", map[string]string{"dedup_window": "a"})
+	assert.Error(t, err)
+
+	h, err = WithDedup(recorder, "test", map[string]string{"dedup_window": "1m"})
+	assert.NoError(t, err)
+	assert.IsType(t, &DedupLogHook{}, h)
+	assert.Equal(t, recorder.Levels(), h.Levels())
+}
+
+func TestDedupLogHook(t *testing.T) {
+	recorder := &testDedupRecorder{}
+	h := newDedupHook(recorder, "test", 50*time.Millisecond)
+
+	newEntry := func(msg string, err error, i int) *logrus.Entry {
+		data := logrus.Fields{"i": i}
+		if err != nil {
+			data[logrus.ErrorKey] = err
+		}
+		return &logrus.Entry{Level: logrus.ErrorLevel, Message: msg, Data: data}
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, h.Fire(newEntry("db down", errors.New("timeout"), i)))
+	}
+	// different message
+	assert.NoError(t, h.Fire(newEntry("cache down", nil, 0)))
+	// different error type
+	assert.NoError(t, h.Fire(newEntry("db down", &testDedupError{}, 0)))
+
+	entries := recorder.get()
+	if assert.Len(t, entries, 3) {
+		assert.Equal(t, "db down", entries[0].Message)
+		assert.Equal(t, 0, entries[0].Data["i"])
+		assert.NotContains(t, entries[0].Data, FieldKeyOccurrences)
+		assert.Equal(t, "cache down", entries[1].Message)
+		assert.Equal(t, "db down", entries[2].Message)
+	}
+
+	// the window closes, the last repeat is sent with the occurrences
+	assert.Eventually(t, func() bool {
+		return len(recorder.get()) == 4
+	}, time.Second, 10*time.Millisecond)
+	last := recorder.get()[3]
+	assert.Equal(t, "db down", last.Message)
+	assert.Equal(t, 4, last.Data["i"])
+	assert.Equal(t, 4, last.Data[FieldKeyOccurrences])
+
+	// the next window
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, recorder.get(), 4)
+	assert.NoError(t, h.Fire(newEntry("db down", errors.New("timeout"), 5)))
+	assert.Len(t, recorder.get(), 5)
+}
+
+// testDedupSerialHook is not safe for concurrent use, as the hooks relying on the logger
+type testDedupSerialHook struct {
+	inflight   int32
+	concurrent bool
+	count      int
+}
+
+func (r *testDedupSerialHook) Fire(entry *logrus.Entry) error {
+	if atomic.AddInt32(&r.inflight, 1) > 1 {
+		r.concurrent = true
+	}
+	time.Sleep(time.Millisecond)
+	r.count++
+	atomic.AddInt32(&r.inflight, -1)
+	return nil
+}
+
+func (r *testDedupSerialHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func TestDedupLogHookSerialized(t *testing.T) {
+	recorder := &testDedupSerialHook{}
+	h := newDedupHook(recorder, "test", time.Millisecond)
+
+	// the repeats flushed by the timers while firing
+	for i := 0; i < 50; i++ {
+		msg := fmt.Sprintf("msg %d", i%5)
+		assert.NoError(t, h.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Message: msg, Data: logrus.Fields{}}))
+		assert.NoError(t, h.Fire(&logrus.Entry{Level: logrus.ErrorLevel, Message: msg, Data: logrus.Fields{}}))
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	h.fireMu.Lock()
+	defer h.fireMu.Unlock()
+	assert.False(t, recorder.concurrent)
+	assert.True(t, recorder.count > 50)
+}
+
+type testDedupError struct{}
+
+func (e *testDedupError) Error() string {
+	return "timeout"
+}
@@ -0,0 +1,343 @@
+package hook
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/lestrrat-go/strftime"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	elasticsearchBulkPath = "/_bulk"
+
+	defaultElasticsearchMaxDocRetries = 3
+)
+
+type ElasticsearchLogHookBuilder struct {
+}
+
+// elasticsearch: index the entries via the bulk api, works with opensearch too
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html
+func (b ElasticsearchLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	if err := validateRequiredHookSettings(name, settings, []string{"url", "index"}); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(settings["url"])
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid elasticsearch url %s, should be like http://127.0.0.1:9200", settings["url"])
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + elasticsearchBulkPath
+
+	poster, err := newHTTPPosterWithSettings(u.String(), settings)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey, ok := settings["api_key"]; ok {
+		if poster.username != "" || poster.authorization != "" {
+			return nil, errors.New("api_key should not be set together with username or bearer_token")
+		}
+		poster.authorization = "ApiKey " + apiKey
+	}
+
+	// index like `logs-app-%Y.%m.%d`, the date is the time of entry
+	index, err := strftime.New(settings["index"])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid elasticsearch index pattern")
+	}
+
+	maxDocRetries, err := getIntSetting(settings, "max_doc_retries", defaultElasticsearchMaxDocRetries)
+	if err != nil {
+		return nil, err
+	}
+	retryBackoff, err := getDurationSetting(settings, "retry_backoff", defaultHTTPRetryBackoff)
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := getBatchSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	config := ElasticsearchHookConfig{
+		DataStream:     getBoolSetting(settings, "data_stream", false),
+		MaxDocRetries:  maxDocRetries,
+		RetryBackoff:   retryBackoff,
+		DeadLetterFile: settings["dead_letter_file"],
+
+		poster: poster,
+		index:  index,
+		batch:  batch,
+	}
+	config.asyncEnable, config.asyncBufferSize, config.asyncBlock = getAsyncSettings(settings)
+
+	return newElasticsearchHook(config), nil
+}
+
+// ElasticsearchHookConfig stores configuration needed to setup the hook
+type ElasticsearchHookConfig struct {
+	// DataStream use the `create` action, which is required by data streams, instead of `index`
+	DataStream bool
+	// MaxDocRetries is the times to retry the documents rejected with 429/5xx
+	MaxDocRetries int
+	RetryBackoff  time.Duration
+	// DeadLetterFile is where the permanently failed documents go, one json per line
+	DeadLetterFile string
+
+	poster *httpPoster
+	index  *strftime.Strftime
+	batch  batchConfig
+
+	asyncEnable     bool
+	asyncBufferSize int
+	asyncBlock      bool
+}
+
+// ElasticsearchLogHook indexes logs into elasticsearch in batches
+type ElasticsearchLogHook struct {
+	poster        *httpPoster
+	index         *strftime.Strftime
+	action        string
+	maxDocRetries int
+	retryBackoff  time.Duration
+
+	deadLetterFile string
+	deadLetterLock sync.Mutex
+
+	batcher *batcher
+}
+
+func newElasticsearchHook(config ElasticsearchHookConfig) *ElasticsearchLogHook {
+	hook := &ElasticsearchLogHook{
+		poster:         config.poster,
+		index:          config.index,
+		action:         "index",
+		maxDocRetries:  config.MaxDocRetries,
+		retryBackoff:   config.RetryBackoff,
+		deadLetterFile: config.DeadLetterFile,
+	}
+	if config.DataStream {
+		hook.action = "create"
+	}
+
+	hook.batcher = newBatcher("elasticsearch", config.batch, hook.encode, hook.send,
+		config.asyncEnable, config.asyncBufferSize, config.asyncBlock)
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *ElasticsearchLogHook) Fire(entry *logrus.Entry) error {
+	return h.batcher.Fire(entry)
+}
+
+// Flush sends all the buffered entries
+func (h *ElasticsearchLogHook) Flush() {
+	h.batcher.Flush()
+}
+
+// Levels returns the available logging levels.
+func (h *ElasticsearchLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// encode renders the action line and the document
+func (h *ElasticsearchLogHook) encode(entry *logrus.Entry) ([]byte, error) {
+	doc, err := jsoniter.Marshal(createElasticsearchDocument(entry))
+	if err != nil {
+		return nil, fmt.Errorf("error creating message for ELASTICSEARCH: %s", err)
+	}
+
+	action, err := jsoniter.Marshal(map[string]map[string]string{
+		h.action: {"_index": h.index.FormatString(entry.Time)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.Write(action)
+	b.WriteByte('\n')
+	b.Write(doc)
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+type elasticsearchBulkResponse struct {
+	Errors bool                                   `json:"errors"`
+	Items  []map[string]elasticsearchBulkItemResp `json:"items"`
+}
+
+type elasticsearchBulkItemResp struct {
+	Index  string              `json:"_index"`
+	Status int                 `json:"status"`
+	Error  jsoniter.RawMessage `json:"error"`
+}
+
+// elasticsearchFailure is the failed document with the status and error from response
+type elasticsearchFailure struct {
+	item   batchItem
+	status int
+	err    jsoniter.RawMessage
+}
+
+// send posts the items, and retry the documents rejected with 429/5xx, at last the failed ones go to dead letter file;
+// the documents failed in all the rounds are counted in the error
+func (h *ElasticsearchLogHook) send(items []batchItem) error {
+	backoff := h.retryBackoff
+	failedCount := 0
+	for i := 0; ; i++ {
+		failed, retryable, err := h.bulk(items)
+		if err != nil {
+			// the whole request fails, after the retries of poster
+			errMsg, _ := jsoniter.Marshal(err.Error())
+			for _, item := range items {
+				failed = append(failed, elasticsearchFailure{item: item, err: errMsg})
+			}
+			h.writeDeadLetters(failed)
+			if failedCount > 0 {
+				return fmt.Errorf("error sending message to ELASTICSEARCH: %s, and %d documents failed before",
+					err, failedCount)
+			}
+			return fmt.Errorf("error sending message to ELASTICSEARCH: %s", err)
+		}
+
+		if len(retryable) > 0 && i < h.maxDocRetries {
+			h.writeDeadLetters(failed)
+			failedCount += len(failed)
+
+			items = make([]batchItem, 0, len(retryable))
+			for _, f := range retryable {
+				items = append(items, f.item)
+			}
+			if backoff > h.poster.maxRetryBackoff {
+				backoff = h.poster.maxRetryBackoff
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+
+		failed = append(failed, retryable...)
+		h.writeDeadLetters(failed)
+		failedCount += len(failed)
+		if failedCount > 0 {
+			return fmt.Errorf("error sending message to ELASTICSEARCH: %d documents failed", failedCount)
+		}
+		return nil
+	}
+}
+
+// bulk posts the items once, returns the documents failed permanently and the ones could be retried
+func (h *ElasticsearchLogHook) bulk(items []batchItem) (failed, retryable []elasticsearchFailure, err error) {
+	var body bytes.Buffer
+	for _, item := range items {
+		body.Write(item.data)
+	}
+
+	respBody, err := h.poster.post(body.Bytes(), "application/x-ndjson")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var resp elasticsearchBulkResponse
+	if err := jsoniter.Unmarshal(respBody, &resp); err != nil {
+		return nil, nil, errors.Wrap(err, "invalid bulk response")
+	}
+	if !resp.Errors {
+		return nil, nil, nil
+	}
+	if len(resp.Items) != len(items) {
+		return nil, nil, fmt.Errorf("the bulk response got %d items, expected %d", len(resp.Items), len(items))
+	}
+
+	for i, respItem := range resp.Items {
+		// only one key, the action
+		for _, result := range respItem {
+			f := elasticsearchFailure{item: items[i], status: result.Status, err: result.Error}
+			switch {
+			case result.Status < 300:
+			case result.Status == 429 || result.Status >= 500:
+				retryable = append(retryable, f)
+			default:
+				failed = append(failed, f)
+			}
+		}
+	}
+	return failed, retryable, nil
+}
+
+// writeDeadLetters appends the failed documents into the dead letter file, one json per line:
+// `{"time": ..., "status": 400, "error": ..., "action": {"index": ...}, "document": {...}}`
+func (h *ElasticsearchLogHook) writeDeadLetters(failures []elasticsearchFailure) {
+	if h.deadLetterFile == "" || len(failures) == 0 {
+		return
+	}
+
+	h.deadLetterLock.Lock()
+	defer h.deadLetterLock.Unlock()
+
+	f, err := os.OpenFile(h.deadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Println("Error during opening elasticsearch dead letter file:", err)
+		return
+	}
+	defer f.Close()
+
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	for _, failure := range failures {
+		// the data is `action\ndocument\n`
+		lines := bytes.SplitN(failure.item.data, []byte{'\n'}, 3)
+		if len(lines) < 2 {
+			continue
+		}
+
+		deadLetter := map[string]interface{}{
+			"time":     now,
+			"status":   failure.status,
+			"action":   jsoniter.RawMessage(lines[0]),
+			"document": jsoniter.RawMessage(lines[1]),
+		}
+		if len(failure.err) > 0 {
+			deadLetter["error"] = failure.err
+		}
+
+		b, err := jsoniter.Marshal(deadLetter)
+		if err != nil {
+			continue
+		}
+		if _, err := f.Write(append(b, '\n')); err != nil {
+			fmt.Println("Error during writing elasticsearch dead letter file:", err)
+			return
+		}
+	}
+}
+
+func createElasticsearchDocument(entry *logrus.Entry) map[string]interface{} {
+	m := make(map[string]interface{}, len(entry.Data)+3)
+	// data streams require the @timestamp
+	m["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
+	m["message"] = entry.Message
+	m["level"] = entry.Level.String()
+	for k, v := range entry.Data {
+		// not to silently overwrite the @timestamp, message and level, same as logrus
+		if k == "@timestamp" || k == "message" || k == "level" {
+			k = "fields." + k
+		}
+		switch v := v.(type) {
+		case error:
+			m[k] = v.Error()
+		default:
+			m[k] = v
+		}
+	}
+	return m
+}
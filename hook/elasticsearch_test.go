@@ -0,0 +1,309 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewElasticsearchHook(t *testing.T) {
+	f := ElasticsearchLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing url or index
+		{map[string]string{}, true},
+		{map[string]string{"url": "http://127.0.0.1:9200"}, true},
+		{map[string]string{"index": "logs"}, true},
+		// normal
+		{map[string]string{"url": "http://127.0.0.1:9200", "index": "logs-app-%Y.%m.%d"}, false},
+		{map[string]string{"url": "http://127.0.0.1:9200", "index": "logs-app", "data_stream": "true", "api_key": "abc"}, false},
+		{map[string]string{"url": "http://127.0.0.1:9200", "index": "logs", "username": "elastic", "password": "123", "dead_letter_file": "dead.log"}, false},
+		// wrong url
+		{map[string]string{"url": "127.0.0.1:9200", "index": "logs"}, true},
+		// wrong index pattern
+		{map[string]string{"url": "http://127.0.0.1:9200", "index": "logs-%"}, true},
+		// api key and basic auth together
+		{map[string]string{"url": "http://127.0.0.1:9200", "index": "logs", "api_key": "abc", "username": "elastic"}, true},
+		// wrong retries, batch
+		{map[string]string{"url": "http://127.0.0.1:9200", "index": "logs", "max_doc_retries": "a"}, true},
+		{map[string]string{"url": "http://127.0.0.1:9200", "index": "logs", "retry_backoff": "a"}, true},
+		{map[string]string{"url": "http://127.0.0.1:9200", "index": "logs", "batch_max_count": "a"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestElasticsearchLogHookLevels(t *testing.T) {
+	h := ElasticsearchLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+type testBulkRequest struct {
+	path    string
+	header  http.Header
+	actions []map[string]map[string]string
+	docs    []map[string]interface{}
+}
+
+// newTestElasticsearchServer is a stand-in of the bulk api,
+// the document with message `reject` fails with 400, `busy` fails with 429 at the first time
+func newTestElasticsearchServer(t *testing.T) (*httptest.Server, func() []testBulkRequest) {
+	var mu sync.Mutex
+	var requests []testBulkRequest
+	busy := map[string]bool{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		req := testBulkRequest{path: r.URL.Path, header: r.Header}
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var action map[string]map[string]string
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &action))
+			assert.True(t, scanner.Scan())
+			var doc map[string]interface{}
+			assert.NoError(t, json.Unmarshal(scanner.Bytes(), &doc))
+
+			req.actions = append(req.actions, action)
+			req.docs = append(req.docs, doc)
+		}
+		requests = append(requests, req)
+
+		resp := map[string]interface{}{"errors": false}
+		var items []map[string]interface{}
+		for i, doc := range req.docs {
+			status := 201
+			var errBody interface{}
+			switch msg := doc["message"].(string); {
+			case msg == "reject":
+				status = 400
+				errBody = map[string]string{"type": "mapper_parsing_exception"}
+			case msg == "busy" && !busy[msg]:
+				busy[msg] = true
+				status = 429
+				errBody = map[string]string{"type": "es_rejected_execution_exception"}
+			}
+			if status != 201 {
+				resp["errors"] = true
+			}
+
+			for action, meta := range req.actions[i] {
+				items = append(items, map[string]interface{}{
+					action: map[string]interface{}{"_index": meta["_index"], "status": status, "error": errBody},
+				})
+			}
+		}
+		resp["items"] = items
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+
+	return server, func() []testBulkRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestElasticsearchLogHookFire(t *testing.T) {
+	server, requests := newTestElasticsearchServer(t)
+	defer server.Close()
+
+	h, err := ElasticsearchLogHookBuilder{}.New("elasticsearch", map[string]string{
+		"url":             server.URL + "/",
+		"index":           "logs-app-%Y.%m.%d",
+		"api_key":         "abc",
+		"batch_max_count": "2",
+		"flush_interval":  "1h",
+	})
+	assert.NoError(t, err)
+
+	ts := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	for _, msg := range []string{"a", "b", "c"} {
+		assert.NoError(t, h.Fire(&logrus.Entry{
+			Message: msg,
+			Level:   logrus.InfoLevel,
+			Time:    ts,
+			Data:    logrus.Fields{"id": 1},
+		}))
+	}
+	h.(*ElasticsearchLogHook).Flush()
+
+	reqs := requests()
+	assert.Len(t, reqs, 2)
+	assert.Equal(t, "/_bulk", reqs[0].path)
+	assert.Equal(t, "application/x-ndjson", reqs[0].header.Get("Content-Type"))
+	assert.Equal(t, "ApiKey abc", reqs[0].header.Get("Authorization"))
+
+	assert.Len(t, reqs[0].docs, 2)
+	assert.Len(t, reqs[1].docs, 1)
+	assert.Equal(t, "logs-app-2020.05.01", reqs[0].actions[0]["index"]["_index"])
+
+	doc := reqs[0].docs[0]
+	assert.Equal(t, "a", doc["message"])
+	assert.Equal(t, "info", doc["level"])
+	assert.Equal(t, "2020-05-01T10:00:00Z", doc["@timestamp"])
+	assert.Equal(t, float64(1), doc["id"])
+}
+
+func TestElasticsearchLogHookDataStream(t *testing.T) {
+	server, requests := newTestElasticsearchServer(t)
+	defer server.Close()
+
+	h, err := ElasticsearchLogHookBuilder{}.New("elasticsearch", map[string]string{
+		"url":          server.URL,
+		"index":        "logs-app-default",
+		"data_stream":  "true",
+		"username":     "elastic",
+		"password":     "123",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+
+	reqs := requests()
+	assert.Len(t, reqs, 1)
+	assert.Equal(t, "logs-app-default", reqs[0].actions[0]["create"]["_index"])
+	assert.Equal(t, "Basic ZWxhc3RpYzoxMjM=", reqs[0].header.Get("Authorization"))
+}
+
+func TestElasticsearchLogHookRetryAndDeadLetter(t *testing.T) {
+	server, requests := newTestElasticsearchServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "elasticsearch")
+	assert.NoError(t, err)
+	deadLetterFile := filepath.Join(dir, "dead.log")
+
+	h, err := ElasticsearchLogHookBuilder{}.New("elasticsearch", map[string]string{
+		"url":              server.URL,
+		"index":            "logs",
+		"retry_backoff":    "1ms",
+		"dead_letter_file": deadLetterFile,
+		"batch_max_count":  "3",
+		"flush_interval":   "1h",
+	})
+	assert.NoError(t, err)
+
+	for _, msg := range []string{"ok", "busy", "reject"} {
+		assert.NoError(t, h.Fire(&logrus.Entry{Message: msg, Data: logrus.Fields{}}))
+	}
+	h.(*ElasticsearchLogHook).Flush()
+
+	// the busy one is retried alone
+	reqs := requests()
+	assert.Len(t, reqs, 2)
+	assert.Len(t, reqs[0].docs, 3)
+	assert.Len(t, reqs[1].docs, 1)
+	assert.Equal(t, "busy", reqs[1].docs[0]["message"])
+
+	// the rejected one goes to the dead letter file
+	content, err := ioutil.ReadFile(deadLetterFile)
+	assert.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSpace(content), []byte{'\n'})
+	assert.Len(t, lines, 1)
+
+	var deadLetter map[string]interface{}
+	assert.NoError(t, json.Unmarshal(lines[0], &deadLetter))
+	assert.Equal(t, float64(400), deadLetter["status"])
+	assert.Equal(t, "reject", deadLetter["document"].(map[string]interface{})["message"])
+	assert.Equal(t, "mapper_parsing_exception", deadLetter["error"].(map[string]interface{})["type"])
+	assert.Contains(t, deadLetter["action"], "index")
+}
+
+func TestElasticsearchLogHookGiveUp(t *testing.T) {
+	server, requests := newTestElasticsearchServer(t)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "elasticsearch")
+	assert.NoError(t, err)
+	deadLetterFile := filepath.Join(dir, "dead.log")
+
+	h, err := ElasticsearchLogHookBuilder{}.New("elasticsearch", map[string]string{
+		"url":              server.URL,
+		"index":            "logs",
+		"max_doc_retries":  "0",
+		"dead_letter_file": deadLetterFile,
+		"async_enable":     "false",
+	})
+	assert.NoError(t, err)
+
+	// no retry, the busy one goes to dead letter file too
+	assert.Error(t, h.Fire(&logrus.Entry{Message: "busy", Data: logrus.Fields{}}))
+	assert.Len(t, requests(), 1)
+
+	content, err := ioutil.ReadFile(deadLetterFile)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"status":429`)
+}
+
+func TestElasticsearchLogHookFailuresCounted(t *testing.T) {
+	server, requests := newTestElasticsearchServer(t)
+	defer server.Close()
+
+	h, err := ElasticsearchLogHookBuilder{}.New("elasticsearch", map[string]string{
+		"url":   server.URL,
+		"index": "logs",
+		// the backoff is capped by max_retry_backoff
+		"retry_backoff":     "1h",
+		"max_retry_backoff": "1ms",
+		"async_enable":      "false",
+	})
+	assert.NoError(t, err)
+	es := h.(*ElasticsearchLogHook)
+
+	var items []batchItem
+	for _, msg := range []string{"ok", "busy", "reject"} {
+		data, err := es.encode(&logrus.Entry{Message: msg, Data: logrus.Fields{}})
+		assert.NoError(t, err)
+		items = append(items, batchItem{data: data})
+	}
+
+	// the rejected one in the first round is counted, though the retry of the busy one succeeds
+	start := time.Now()
+	err = es.send(items)
+	assert.EqualError(t, err, "error sending message to ELASTICSEARCH: 1 documents failed")
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Len(t, requests(), 2)
+}
+
+func TestCreateElasticsearchDocument(t *testing.T) {
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	doc := createElasticsearchDocument(&logrus.Entry{Message: "hello", Level: logrus.InfoLevel, Time: now,
+		Data: logrus.Fields{"a": 1, "error": errors.New("oops"), "message": "m", "level": 1, "@timestamp": "t"}})
+	assert.Equal(t, map[string]interface{}{
+		"@timestamp":        "2020-05-01T10:00:00Z",
+		"message":           "hello",
+		"level":             "info",
+		"a":                 1,
+		"error":             "oops",
+		"fields.message":    "m",
+		"fields.level":      1,
+		"fields.@timestamp": "t",
+	}, doc)
+}
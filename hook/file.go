@@ -2,7 +2,9 @@ package hook
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -15,6 +17,9 @@ import (
 
 type FileLogHookBuilder struct {
 	Formatter logrus.Formatter
+	// Writers shares the rotated file writers by the path if not nil, so the hooks of the same file
+	// with different formatters do not open it twice
+	Writers map[string]io.Writer
 }
 
 // file hook : https://github.com/rifflock/lfshook
@@ -55,22 +60,24 @@ func (b FileLogHookBuilder) New(name string, settings map[string]string) (logrus
 		logPath = fmt.Sprintf("%s/%s", rawPath, filename)
 	}
 
-	return newFileHook(logPath, keep, b.Formatter, asyncEnable, asyncBufferSize, asyncBlock)
+	writer, err := b.getWriter(logPath, keep)
+	if err != nil {
+		return nil, err
+	}
+	return newFileHook(writer, b.Formatter, asyncEnable, asyncBufferSize, asyncBlock), nil
 }
 
-type FileLogHook struct {
-	fireChannel     chan *logrus.Entry
-	asyncEnable     bool
-	asyncBufferSize int
-	asyncBlock      bool
-
-	// loghook *logrus.Hook
-	loghook *lfshook.LfsHook
-}
+// getWriter returns the shared writer of the path, or creates the rotated file writer
+func (b FileLogHookBuilder) getWriter(path string, keepDays int) (io.Writer, error) {
+	key := path
+	if abs, err := filepath.Abs(path); err == nil {
+		key = abs
+	}
+	if w, ok := b.Writers[key]; ok {
+		return w, nil
+	}
 
-func newFileHook(path string, keepDays int, formatter logrus.Formatter,
-	asyncEnable bool, asyncBufferSize int, asyncBlock bool) (*FileLogHook, error) {
-	// create rotate file hook
+	// create rotate file writer
 	rotateTime := 24 * time.Hour
 	writer, err := rotatelogs.New(
 		path+".%Y%m%d",
@@ -81,6 +88,24 @@ func newFileHook(path string, keepDays int, formatter logrus.Formatter,
 	if err != nil {
 		return nil, err
 	}
+	if b.Writers != nil {
+		b.Writers[key] = writer
+	}
+	return writer, nil
+}
+
+type FileLogHook struct {
+	fireChannel     chan *logrus.Entry
+	asyncEnable     bool
+	asyncBufferSize int
+	asyncBlock      bool
+
+	// loghook *logrus.Hook
+	loghook *lfshook.LfsHook
+}
+
+func newFileHook(writer io.Writer, formatter logrus.Formatter,
+	asyncEnable bool, asyncBufferSize int, asyncBlock bool) *FileLogHook {
 	loghook := lfshook.NewHook(
 		lfshook.WriterMap{
 			logrus.InfoLevel:  writer,
@@ -101,7 +126,7 @@ func newFileHook(path string, keepDays int, formatter logrus.Formatter,
 		fmt.Printf("init a async logger enable=%t buffer_size=%d, block=%t\n", asyncEnable, asyncBufferSize, asyncBlock)
 	}
 
-	return hook, nil
+	return hook
 }
 
 func (f *FileLogHook) makeAsync() {
@@ -119,12 +144,17 @@ func (f *FileLogHook) makeAsync() {
 // Fire is called when a log event is fired.
 func (f *FileLogHook) Fire(entry *logrus.Entry) error {
 	if f.fireChannel != nil { // Async mode.
+		// logrus will set a pooled Buffer into the entry after the hooks fired, and put it back after written,
+		// send a copy, so the formatter in the worker goroutine will not write into it
+		e := *entry
+		e.Buffer = nil
+
 		select {
-		case f.fireChannel <- entry: // try and put into chan, if fail will to default
+		case f.fireChannel <- &e: // try and put into chan, if fail will to default
 		default:
 			if f.asyncBlock {
 				fmt.Println("the log buffered chan is full! will block")
-				f.fireChannel <- entry // Blocks the goroutine because buffer is full.
+				f.fireChannel <- &e // Blocks the goroutine because buffer is full.
 				return nil
 			}
 			fmt.Println("the log buffered chan is full! will drop")
@@ -1,9 +1,19 @@
 package hook
 
 import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/wklken/logging-go/formatter"
 )
 
 func TestNewFileHook(t *testing.T) {
@@ -37,3 +47,71 @@ func TestNewFileHook(t *testing.T) {
 		}
 	}
 }
+
+func TestFileLogHookSharedWriters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	settings := map[string]string{"name": "app.log", "path": dir, "async_enable": "false"}
+	writers := map[string]io.Writer{}
+	h1, err := FileLogHookBuilder{Formatter: &logrus.JSONFormatter{}, Writers: writers}.New("file", settings)
+	assert.NoError(t, err)
+	h2, err := FileLogHookBuilder{Formatter: &logrus.TextFormatter{}, Writers: writers}.New("file", settings)
+	assert.NoError(t, err)
+	assert.Len(t, writers, 1)
+
+	// the same writer, the formatter of each hook
+	assert.NoError(t, h1.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "json", Data: logrus.Fields{}}))
+	assert.NoError(t, h2.Fire(&logrus.Entry{Level: logrus.InfoLevel, Message: "text", Data: logrus.Fields{}}))
+	files, _ := filepath.Glob(dir + "/app.log.*")
+	if !assert.Len(t, files, 1) {
+		return
+	}
+	content, err := ioutil.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"msg":"json"`)
+	assert.Contains(t, string(content), `msg=text`)
+
+	// not shared without the writers
+	_, err = FileLogHookBuilder{}.New("file", settings)
+	assert.NoError(t, err)
+	assert.Len(t, writers, 1)
+}
+
+// run with -race, the entry.Buffer is put back into the pool by logrus while the async hook formats the entry
+func TestFileLogHookAsyncPooledBuffer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := FileLogHookBuilder{Formatter: &formatter.LogfmtFormatter{}}.New("file", map[string]string{"name": "app.log", "path": dir})
+	assert.NoError(t, err)
+
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(h)
+	for i := 0; i < 50; i++ {
+		logger.WithField("i", i).Info("hello")
+	}
+
+	var lines []string
+	for start := time.Now(); time.Since(start) < time.Second; time.Sleep(10 * time.Millisecond) {
+		files, _ := filepath.Glob(dir + "/app.log.*")
+		if len(files) == 1 {
+			content, _ := ioutil.ReadFile(files[0])
+			lines = strings.Split(strings.TrimSpace(string(content)), "\n")
+			if len(lines) == 50 {
+				break
+			}
+		}
+	}
+	assert.Len(t, lines, 50)
+	for _, line := range lines {
+		assert.Contains(t, line, "level=info msg=hello i=")
+	}
+}
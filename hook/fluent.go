@@ -0,0 +1,267 @@
+package hook
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/tinylib/msgp/msgp"
+)
+
+const (
+	defaultFluentAckTimeout = 5 * time.Second
+
+	// the ext type of EventTime in forward protocol
+	fluentEventTimeType = 0
+)
+
+type FluentLogHookBuilder struct {
+}
+
+// fluent: the forward protocol of fluentd/fluent-bit
+// https://github.com/fluent/fluentd/wiki/Forward-Protocol-Specification-v1
+func (b FluentLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	if err := validateRequiredHookSettings(name, settings, []string{"address", "tag"}); err != nil {
+		return nil, err
+	}
+
+	network, ok := settings["network"]
+	if !ok {
+		network = "tcp"
+	}
+	if network != "tcp" && network != "unix" {
+		return nil, fmt.Errorf("unsupported fluent network %s, should be tcp or unix", network)
+	}
+
+	useTLS := getBoolSetting(settings, "tls", false)
+	if useTLS && network != "tcp" {
+		return nil, fmt.Errorf("tls is only supported by tcp, not %s", network)
+	}
+
+	writer, err := newNetWriterWithSettings(network, settings["address"], useTLS, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	ackTimeout, err := getDurationSetting(settings, "ack_timeout", defaultFluentAckTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := getBatchSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	config := FluentHookConfig{
+		Tag:           settings["tag"],
+		Ack:           getBoolSetting(settings, "ack", false),
+		AckTimeout:    ackTimeout,
+		TimeAsInteger: getBoolSetting(settings, "time_as_integer", false),
+
+		writer: writer,
+		batch:  batch,
+	}
+	config.asyncEnable, config.asyncBufferSize, config.asyncBlock = getAsyncSettings(settings)
+
+	return newFluentHook(config), nil
+}
+
+// FluentHookConfig stores configuration needed to setup the hook
+type FluentHookConfig struct {
+	Tag string
+	// Ack requires the server to send back an ack for each chunk, the chunk will be resent if no ack received
+	Ack        bool
+	AckTimeout time.Duration
+	// TimeAsInteger sends the time in seconds, for fluentd before v0.14 which doesn't support the EventTime
+	TimeAsInteger bool
+
+	writer *netWriter
+	batch  batchConfig
+
+	asyncEnable     bool
+	asyncBufferSize int
+	asyncBlock      bool
+}
+
+// FluentLogHook sends logs to fluentd/fluent-bit in the Forward mode, `[tag, [[time, record], ...], option]`
+type FluentLogHook struct {
+	writer        *netWriter
+	tag           string
+	ack           bool
+	ackTimeout    time.Duration
+	timeAsInteger bool
+
+	batcher *batcher
+}
+
+func newFluentHook(config FluentHookConfig) *FluentLogHook {
+	hook := &FluentLogHook{
+		writer:        config.writer,
+		tag:           config.Tag,
+		ack:           config.Ack,
+		ackTimeout:    config.AckTimeout,
+		timeAsInteger: config.TimeAsInteger,
+	}
+	hook.batcher = newBatcher("fluent", config.batch, hook.encode, hook.send,
+		config.asyncEnable, config.asyncBufferSize, config.asyncBlock)
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *FluentLogHook) Fire(entry *logrus.Entry) error {
+	return h.batcher.Fire(entry)
+}
+
+// Flush sends all the buffered entries
+func (h *FluentLogHook) Flush() {
+	h.batcher.Flush()
+}
+
+// Levels returns the available logging levels.
+func (h *FluentLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// encode renders the entry into msgpack `[time, record]`
+func (h *FluentLogHook) encode(entry *logrus.Entry) ([]byte, error) {
+	b := msgp.AppendArrayHeader(nil, 2)
+
+	if h.timeAsInteger {
+		b = msgp.AppendInt64(b, entry.Time.Unix())
+	} else {
+		var err error
+		b, err = msgp.AppendExtension(b, &fluentEventTime{entry.Time})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	record := make(map[string]interface{}, len(entry.Data)+2)
+	record["message"] = entry.Message
+	record["level"] = entry.Level.String()
+	for k, v := range entry.Data {
+		// not to silently overwrite the message and level, same as logrus
+		if k == "message" || k == "level" {
+			k = "fields." + k
+		}
+		record[k] = v
+	}
+
+	b = msgp.AppendMapHeader(b, uint32(len(record)))
+	for _, k := range sortedKeys(record) {
+		b = msgp.AppendString(b, k)
+		b = appendMsgpValue(b, record[k])
+	}
+	return b, nil
+}
+
+func (h *FluentLogHook) send(items []batchItem) error {
+	b := msgp.AppendArrayHeader(nil, 3)
+	b = msgp.AppendString(b, h.tag)
+	b = msgp.AppendArrayHeader(b, uint32(len(items)))
+	for _, item := range items {
+		b = append(b, item.data...)
+	}
+
+	if !h.ack {
+		b = msgp.AppendMapHeader(b, 1)
+		b = msgp.AppendString(b, "size")
+		b = msgp.AppendInt(b, len(items))
+
+		if err := h.writer.writeAndRead(b, nil); err != nil {
+			return fmt.Errorf("error sending message to FLUENT: %s", err)
+		}
+		return nil
+	}
+
+	chunk, err := newFluentChunkID()
+	if err != nil {
+		return err
+	}
+	b = msgp.AppendMapHeader(b, 2)
+	b = msgp.AppendString(b, "size")
+	b = msgp.AppendInt(b, len(items))
+	b = msgp.AppendString(b, "chunk")
+	b = msgp.AppendString(b, chunk)
+
+	err = h.writer.writeAndRead(b, func(conn net.Conn) error {
+		return h.readAck(conn, chunk)
+	})
+	if err != nil {
+		return fmt.Errorf("error sending message to FLUENT: %s", err)
+	}
+	return nil
+}
+
+// readAck reads the response `{"ack": chunk}` from server
+func (h *FluentLogHook) readAck(conn net.Conn, chunk string) error {
+	_ = conn.SetReadDeadline(time.Now().Add(h.ackTimeout))
+	defer conn.SetReadDeadline(time.Time{}) // nolint:errcheck
+
+	resp, err := msgp.NewReaderSize(conn, 64).ReadIntf()
+	if err != nil {
+		return errors.Wrap(err, "read ack fail")
+	}
+	m, ok := resp.(map[string]interface{})
+	if !ok || m["ack"] != chunk {
+		return fmt.Errorf("invalid ack %v, expected chunk %s", resp, chunk)
+	}
+	return nil
+}
+
+// appendMsgpValue appends the value, the error/time will be string, and the unsupported types will be `%v`
+func appendMsgpValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case error:
+		return msgp.AppendString(b, v.Error())
+	case time.Time:
+		return msgp.AppendString(b, v.Format(time.RFC3339Nano))
+	}
+
+	nb, err := msgp.AppendIntf(b, v)
+	if err != nil {
+		return msgp.AppendString(b, fmt.Sprintf("%v", v))
+	}
+	return nb
+}
+
+func newFluentChunkID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.Wrap(err, "generate chunk id fail")
+	}
+	return base64.StdEncoding.EncodeToString(id), nil
+}
+
+// fluentEventTime is the EventTime ext type, seconds and nanoseconds in 32-bit big-endian
+type fluentEventTime struct {
+	time.Time
+}
+
+func (t *fluentEventTime) ExtensionType() int8 {
+	return fluentEventTimeType
+}
+
+func (t *fluentEventTime) Len() int {
+	return 8
+}
+
+func (t *fluentEventTime) MarshalBinaryTo(b []byte) error {
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return nil
+}
+
+func (t *fluentEventTime) UnmarshalBinary(b []byte) error {
+	if len(b) != 8 {
+		return fmt.Errorf("invalid EventTime length %d", len(b))
+	}
+	t.Time = time.Unix(int64(binary.BigEndian.Uint32(b)), int64(binary.BigEndian.Uint32(b[4:])))
+	return nil
+}
@@ -0,0 +1,208 @@
+package hook
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestNewFluentHook(t *testing.T) {
+	f := FluentLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing address, tag
+		{map[string]string{}, true},
+		{map[string]string{"address": "127.0.0.1:24224"}, true},
+		// normal
+		{map[string]string{"address": "127.0.0.1:24224", "tag": "app.log"}, false},
+		{map[string]string{"address": "/var/run/fluent.sock", "network": "unix", "tag": "app.log", "ack": "true"}, false},
+		{map[string]string{"address": "127.0.0.1:24224", "tag": "app.log", "tls": "true"}, false},
+		// wrong network
+		{map[string]string{"address": "127.0.0.1:24224", "tag": "app.log", "network": "udp"}, true},
+		{map[string]string{"address": "/var/run/fluent.sock", "tag": "app.log", "network": "unix", "tls": "true"}, true},
+		// wrong settings
+		{map[string]string{"address": "127.0.0.1:24224", "tag": "app.log", "ack_timeout": "a"}, true},
+		{map[string]string{"address": "127.0.0.1:24224", "tag": "app.log", "batch_max_count": "a"}, true},
+		{map[string]string{"address": "127.0.0.1:24224", "tag": "app.log", "max_retries": "a"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestFluentLogHookLevels(t *testing.T) {
+	h := FluentLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+func TestFluentEventTime(t *testing.T) {
+	now := time.Unix(1585729801, 123456789)
+	b, err := msgp.AppendExtension(nil, &fluentEventTime{now})
+	assert.NoError(t, err)
+	// fixext8, type 0, then 8 bytes
+	assert.Equal(t, []byte{0xd7, 0x00, 0x5e, 0x84, 0x51, 0x09, 0x07, 0x5b, 0xcd, 0x15}, b)
+
+	var et fluentEventTime
+	_, err = msgp.ReadExtensionBytes(b, &et)
+	assert.NoError(t, err)
+	assert.True(t, now.Equal(et.Time))
+}
+
+type testFluentStruct struct {
+	A int
+}
+
+func TestAppendMsgpValue(t *testing.T) {
+	var data = []struct {
+		value    interface{}
+		expected interface{}
+	}{
+		{"a", "a"},
+		{int64(1), int64(1)},
+		{errors.New("wild walrus"), "wild walrus"},
+		{time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC), "2020-04-01T00:00:00Z"},
+		{testFluentStruct{A: 1}, "{1}"},
+		{map[string]interface{}{"e": errors.New("nested")}, "map[e:nested]"},
+	}
+	for _, d := range data {
+		b := appendMsgpValue(nil, d.value)
+		v, _, err := msgp.ReadIntfBytes(b)
+		assert.NoError(t, err)
+		assert.Equal(t, d.expected, v)
+	}
+}
+
+// fluentServer reads the forward messages, and sends back the ack if required
+func fluentServer(t *testing.T, ln net.Listener, ack bool) <-chan []interface{} {
+	received := make(chan []interface{}, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := msgp.NewReader(conn)
+		for {
+			msg, err := reader.ReadIntf()
+			if err != nil {
+				return
+			}
+			forward := msg.([]interface{})
+			if ack {
+				option := forward[2].(map[string]interface{})
+				resp, _ := msgp.AppendIntf(nil, map[string]interface{}{"ack": option["chunk"]})
+				if _, err := conn.Write(resp); err != nil {
+					return
+				}
+			}
+			received <- forward
+		}
+	}()
+	return received
+}
+
+func TestFluentLogHookFire(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	received := fluentServer(t, ln, false)
+
+	h, err := FluentLogHookBuilder{}.New("fluent", map[string]string{
+		"address":         ln.Addr().String(),
+		"tag":             "app.log",
+		"batch_max_count": "2",
+	})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	for _, msg := range []string{"a", "b"} {
+		assert.NoError(t, h.Fire(&logrus.Entry{Message: msg, Level: logrus.InfoLevel, Time: now,
+			Data: logrus.Fields{"x": 1, "message": "m", "level": 1}}))
+	}
+
+	select {
+	case forward := <-received:
+		assert.Equal(t, "app.log", forward[0])
+
+		entries := forward[1].([]interface{})
+		assert.Len(t, entries, 2)
+
+		first := entries[0].([]interface{})
+		eventTime := first[0].(*msgp.RawExtension)
+		assert.Equal(t, int8(fluentEventTimeType), eventTime.Type)
+		record := first[1].(map[string]interface{})
+		assert.Equal(t, "a", record["message"])
+		assert.Equal(t, "info", record["level"])
+		assert.Equal(t, int64(1), record["x"])
+		// the clashing fields are prefixed
+		assert.Equal(t, "m", record["fields.message"])
+		assert.Equal(t, int64(1), record["fields.level"])
+
+		assert.Equal(t, map[string]interface{}{"size": int64(2)}, forward[2])
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestFluentLogHookFireAck(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	received := fluentServer(t, ln, true)
+
+	h, err := FluentLogHookBuilder{}.New("fluent", map[string]string{
+		"address":         ln.Addr().String(),
+		"tag":             "app.log",
+		"ack":             "true",
+		"time_as_integer": "true",
+		"async_enable":    "false",
+	})
+	assert.NoError(t, err)
+
+	now := time.Now()
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "a", Level: logrus.InfoLevel, Time: now, Data: logrus.Fields{}}))
+
+	forward := <-received
+	first := forward[1].([]interface{})[0].([]interface{})
+	assert.Equal(t, now.Unix(), first[0])
+	assert.Contains(t, forward[2], "chunk")
+}
+
+func TestFluentLogHookFireAckTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	// the server never sends ack
+	fluentServer(t, ln, false)
+
+	h, err := FluentLogHookBuilder{}.New("fluent", map[string]string{
+		"address":      ln.Addr().String(),
+		"tag":          "app.log",
+		"ack":          "true",
+		"ack_timeout":  "10ms",
+		"max_retries":  "0",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.Error(t, h.Fire(&logrus.Entry{Message: "a", Level: logrus.InfoLevel, Time: time.Now(), Data: logrus.Fields{}}))
+}
@@ -0,0 +1,231 @@
+package hook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wklken/logging-go/formatter"
+)
+
+const (
+	GELFCompressionGzip = "gzip"
+	GELFCompressionZlib = "zlib"
+	GELFCompressionNone = "none"
+
+	// 1420 is for the WAN, it can be 8192 in the LAN
+	defaultGELFChunkSize = 1420
+	gelfMaxChunks        = 128
+	// magic(2) + message id(8) + sequence number(1) + sequence count(1)
+	gelfChunkHeaderSize = 12
+)
+
+var gelfChunkMagic = []byte{0x1e, 0x0f}
+
+type GELFLogHookBuilder struct {
+}
+
+// gelf: send the GELF 1.1 messages to graylog, over udp(chunked and compressed) or tcp(null byte framing)
+// https://go2docs.graylog.org/current/getting_in_log_data/gelf.html
+func (b GELFLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	if err := validateRequiredHookSettings(name, settings, []string{"address"}); err != nil {
+		return nil, err
+	}
+
+	network, ok := settings["network"]
+	if !ok {
+		network = "udp"
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unsupported gelf network %s, should be udp or tcp", network)
+	}
+
+	compression, ok := settings["compression"]
+	if !ok {
+		compression = GELFCompressionGzip
+		if network == "tcp" {
+			compression = GELFCompressionNone
+		}
+	}
+	switch compression {
+	case GELFCompressionGzip, GELFCompressionZlib, GELFCompressionNone:
+	default:
+		return nil, fmt.Errorf("unsupported gelf compression %s, should be gzip, zlib or none", compression)
+	}
+	if network == "tcp" && compression != GELFCompressionNone {
+		return nil, errors.New("gelf over tcp does not support compression")
+	}
+
+	chunkSize, err := getIntSetting(settings, "chunk_size", defaultGELFChunkSize)
+	if err != nil {
+		return nil, err
+	}
+	if chunkSize <= gelfChunkHeaderSize {
+		return nil, fmt.Errorf("chunk_size should be greater than %d", gelfChunkHeaderSize)
+	}
+
+	useTLS := getBoolSetting(settings, "tls", false)
+	if useTLS && network != "tcp" {
+		return nil, fmt.Errorf("tls is only supported by tcp, not %s", network)
+	}
+
+	writer, err := newNetWriterWithSettings(network, settings["address"], useTLS, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	config := GELFHookConfig{
+		Compression: compression,
+		ChunkSize:   chunkSize,
+
+		writer:    writer,
+		formatter: &formatter.GELFFormatter{Hostname: settings["hostname"]},
+	}
+	config.asyncEnable, config.asyncBufferSize, config.asyncBlock = getAsyncSettings(settings)
+
+	return newGELFHook(config), nil
+}
+
+// GELFHookConfig stores configuration needed to setup the hook
+type GELFHookConfig struct {
+	// Compression is for udp, gzip, zlib or none
+	Compression string
+	// ChunkSize is the max size of the udp datagram, the message larger than it will be chunked
+	ChunkSize int
+
+	writer    *netWriter
+	formatter logrus.Formatter
+
+	asyncEnable     bool
+	asyncBufferSize int
+	asyncBlock      bool
+}
+
+// GELFLogHook sends logs to graylog
+type GELFLogHook struct {
+	writer      *netWriter
+	formatter   logrus.Formatter
+	compression string
+	chunkSize   int
+
+	async *asyncSender
+}
+
+func newGELFHook(config GELFHookConfig) *GELFLogHook {
+	hook := &GELFLogHook{
+		writer:      config.writer,
+		formatter:   config.formatter,
+		compression: config.Compression,
+		chunkSize:   config.ChunkSize,
+	}
+
+	if config.asyncEnable {
+		hook.async = newAsyncSender("gelf", config.asyncBufferSize, config.asyncBlock, hook.send)
+	}
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *GELFLogHook) Fire(entry *logrus.Entry) error {
+	if h.async != nil {
+		return h.async.Fire(entry)
+	}
+	return h.send(entry)
+}
+
+// Levels returns the available logging levels.
+func (h *GELFLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *GELFLogHook) send(entry *logrus.Entry) error {
+	msg, err := h.formatter.Format(entry)
+	if err != nil {
+		return fmt.Errorf("error creating message for GELF: %s", err)
+	}
+	msg = bytes.TrimRight(msg, "\n")
+
+	// tcp: each message ends with a null byte
+	if h.writer.isStream() {
+		if _, err := h.writer.Write(append(msg, 0)); err != nil {
+			return fmt.Errorf("error sending message to GELF: %s", err)
+		}
+		return nil
+	}
+
+	if msg, err = compressGELF(h.compression, msg); err != nil {
+		return fmt.Errorf("error compressing message for GELF: %s", err)
+	}
+
+	chunks, err := chunkGELF(msg, h.chunkSize)
+	if err != nil {
+		return fmt.Errorf("error sending message to GELF: %s", err)
+	}
+	for _, chunk := range chunks {
+		if _, err := h.writer.Write(chunk); err != nil {
+			return fmt.Errorf("error sending message to GELF: %s", err)
+		}
+	}
+	return nil
+}
+
+func compressGELF(compression string, msg []byte) ([]byte, error) {
+	var b bytes.Buffer
+	var w io.WriteCloser
+	switch compression {
+	case GELFCompressionGzip:
+		w = gzip.NewWriter(&b)
+	case GELFCompressionZlib:
+		w = zlib.NewWriter(&b)
+	default:
+		return msg, nil
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// chunkGELF splits the message into chunks if it's larger than the chunk size, at most 128 chunks
+func chunkGELF(msg []byte, chunkSize int) ([][]byte, error) {
+	if len(msg) <= chunkSize {
+		return [][]byte{msg}, nil
+	}
+
+	dataSize := chunkSize - gelfChunkHeaderSize
+	count := (len(msg) + dataSize - 1) / dataSize
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("message too large, %d bytes need %d chunks, max is %d", len(msg), count, gelfMaxChunks)
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * dataSize
+		if end > len(msg) {
+			end = len(msg)
+		}
+
+		chunk := make([]byte, 0, gelfChunkHeaderSize+end-i*dataSize)
+		chunk = append(chunk, gelfChunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, msg[i*dataSize:end]...)
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
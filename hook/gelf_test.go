@@ -0,0 +1,203 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewGELFHook(t *testing.T) {
+	f := GELFLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing address
+		{map[string]string{}, true},
+		// normal
+		{map[string]string{"address": "127.0.0.1:12201"}, false},
+		{map[string]string{"address": "127.0.0.1:12201", "compression": "zlib", "chunk_size": "8192", "hostname": "web1"}, false},
+		{map[string]string{"address": "127.0.0.1:12201", "network": "tcp", "tls": "true"}, false},
+		// wrong network, compression
+		{map[string]string{"address": "127.0.0.1:12201", "network": "unix"}, true},
+		{map[string]string{"address": "127.0.0.1:12201", "compression": "lz4"}, true},
+		{map[string]string{"address": "127.0.0.1:12201", "network": "tcp", "compression": "gzip"}, true},
+		// wrong chunk size
+		{map[string]string{"address": "127.0.0.1:12201", "chunk_size": "a"}, true},
+		{map[string]string{"address": "127.0.0.1:12201", "chunk_size": "12"}, true},
+		// tls over udp
+		{map[string]string{"address": "127.0.0.1:12201", "tls": "true"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestGELFLogHookLevels(t *testing.T) {
+	h := GELFLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+func TestChunkGELF(t *testing.T) {
+	// no need to chunk
+	chunks, err := chunkGELF([]byte("hello"), 20)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("hello")}, chunks)
+
+	// 8 bytes data each chunk
+	msg := []byte("0123456789abcdefghijk")
+	chunks, err = chunkGELF(msg, 20)
+	assert.NoError(t, err)
+	assert.Len(t, chunks, 3)
+
+	var joined []byte
+	for i, chunk := range chunks {
+		assert.Equal(t, gelfChunkMagic, chunk[:2])
+		assert.Equal(t, chunks[0][2:10], chunk[2:10])
+		assert.Equal(t, byte(i), chunk[10])
+		assert.Equal(t, byte(3), chunk[11])
+		joined = append(joined, chunk[12:]...)
+	}
+	assert.Equal(t, msg, joined)
+
+	// too many chunks
+	_, err = chunkGELF(make([]byte, 129), 13)
+	assert.Error(t, err)
+}
+
+// readGELFDatagrams reads the datagrams, join the chunks and decompress
+func readGELFDatagrams(t *testing.T, conn net.PacketConn, decompress func(b []byte) ([]byte, error)) map[string]interface{} {
+	var msg []byte
+	buf := make([]byte, 65536)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if !assert.NoError(t, err) {
+			return nil
+		}
+
+		if !bytes.HasPrefix(buf[:n], gelfChunkMagic) {
+			msg = append([]byte{}, buf[:n]...)
+			break
+		}
+		msg = append(msg, buf[12:n]...)
+		if buf[10] == buf[11]-1 {
+			break
+		}
+	}
+
+	data, err := decompress(msg)
+	assert.NoError(t, err)
+
+	var m map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &m))
+	return m
+}
+
+func TestGELFLogHookFireUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// gzip, chunked
+	h, err := GELFLogHookBuilder{}.New("gelf", map[string]string{
+		"address":      conn.LocalAddr().String(),
+		"chunk_size":   "100",
+		"hostname":     "web1",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+
+	long := strings.Repeat("a long message ", 100)
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: long, Level: logrus.ErrorLevel, Data: logrus.Fields{"id": 1}}))
+
+	m := readGELFDatagrams(t, conn, func(b []byte) ([]byte, error) {
+		r, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(r)
+	})
+	assert.Equal(t, strings.TrimSpace(long), m["short_message"])
+	assert.Equal(t, "web1", m["host"])
+	assert.Equal(t, float64(3), m["level"])
+	assert.Equal(t, float64(1), m["__id"])
+
+	// zlib
+	h, err = GELFLogHookBuilder{}.New("gelf", map[string]string{
+		"address":      conn.LocalAddr().String(),
+		"compression":  "zlib",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+
+	m = readGELFDatagrams(t, conn, func(b []byte) ([]byte, error) {
+		r, err := zlib.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		return ioutil.ReadAll(r)
+	})
+	assert.Equal(t, "hello", m["short_message"])
+
+	// none
+	h, err = GELFLogHookBuilder{}.New("gelf", map[string]string{
+		"address":      conn.LocalAddr().String(),
+		"compression":  "none",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+
+	m = readGELFDatagrams(t, conn, func(b []byte) ([]byte, error) { return b, nil })
+	assert.Equal(t, "hello", m["short_message"])
+}
+
+func TestGELFLogHookFireTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	received := acceptOne(ln, func(reader *bufio.Reader) (string, error) {
+		return reader.ReadString(0)
+	})
+
+	h, err := GELFLogHookBuilder{}.New("gelf", map[string]string{
+		"address":      ln.Addr().String(),
+		"network":      "tcp",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, byte(0), msg[len(msg)-1])
+
+		var m map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(msg[:len(msg)-1]), &m))
+		assert.Equal(t, "hello", m["short_message"])
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
@@ -0,0 +1,319 @@
+package hook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	HTTPEncodingJSON   = "json"
+	HTTPEncodingNDJSON = "ndjson"
+
+	defaultHTTPTimeout         = 10 * time.Second
+	defaultHTTPMaxRetries      = 3
+	defaultHTTPRetryBackoff    = time.Second
+	defaultHTTPMaxRetryBackoff = 30 * time.Second
+
+	// the settings with this prefix will be sent as http headers, e.g. `header_X-Scope-OrgID: tenant1`
+	httpHeaderSettingPrefix = "header_"
+)
+
+type HTTPLogHookBuilder struct {
+	Formatter logrus.Formatter
+}
+
+// http: post the batched entries to the url, as json array or ndjson
+func (b HTTPLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	if err := validateRequiredHookSettings(name, settings, []string{"url"}); err != nil {
+		return nil, err
+	}
+
+	encoding, ok := settings["encoding"]
+	if !ok {
+		encoding = HTTPEncodingJSON
+	}
+	if encoding != HTTPEncodingJSON && encoding != HTTPEncodingNDJSON {
+		return nil, fmt.Errorf("unsupported http encoding %s, should be json or ndjson", encoding)
+	}
+
+	poster, err := newHTTPPosterWithSettings(settings["url"], settings)
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := getBatchSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	formatter := b.Formatter
+	if formatter == nil {
+		formatter = &logrus.JSONFormatter{}
+	}
+
+	asyncEnable, asyncBufferSize, asyncBlock := getAsyncSettings(settings)
+
+	return newHTTPHook(poster, encoding, formatter, batch, asyncEnable, asyncBufferSize, asyncBlock), nil
+}
+
+// HTTPLogHook posts the logs to a http endpoint in batches
+type HTTPLogHook struct {
+	poster    *httpPoster
+	encoding  string
+	formatter logrus.Formatter
+
+	batcher *batcher
+}
+
+func newHTTPHook(poster *httpPoster, encoding string, formatter logrus.Formatter, batch batchConfig,
+	asyncEnable bool, asyncBufferSize int, asyncBlock bool) *HTTPLogHook {
+	hook := &HTTPLogHook{
+		poster:    poster,
+		encoding:  encoding,
+		formatter: formatter,
+	}
+	hook.batcher = newBatcher("http", batch, hook.encode, hook.send, asyncEnable, asyncBufferSize, asyncBlock)
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *HTTPLogHook) Fire(entry *logrus.Entry) error {
+	return h.batcher.Fire(entry)
+}
+
+// Flush sends all the buffered entries
+func (h *HTTPLogHook) Flush() {
+	h.batcher.Flush()
+}
+
+// Levels returns the available logging levels.
+func (h *HTTPLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *HTTPLogHook) encode(entry *logrus.Entry) ([]byte, error) {
+	msg, err := h.formatter.Format(entry)
+	if err != nil {
+		return nil, fmt.Errorf("error creating message for HTTP: %s", err)
+	}
+	return bytes.TrimRight(msg, "\n"), nil
+}
+
+func (h *HTTPLogHook) send(items []batchItem) error {
+	var body bytes.Buffer
+	contentType := "application/json"
+
+	if h.encoding == HTTPEncodingNDJSON {
+		contentType = "application/x-ndjson"
+		for _, item := range items {
+			body.Write(item.data)
+			body.WriteByte('\n')
+		}
+	} else {
+		body.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				body.WriteByte(',')
+			}
+			body.Write(item.data)
+		}
+		body.WriteByte(']')
+	}
+
+	if _, err := h.poster.post(body.Bytes(), contentType); err != nil {
+		return fmt.Errorf("error sending message to HTTP: %s", err)
+	}
+	return nil
+}
+
+// httpPoster posts the body to the url with the auth/headers/gzip settings, and retry on 429 and 5xx
+type httpPoster struct {
+	client *http.Client
+	url    string
+	method string
+
+	headers       map[string]string
+	username      string
+	password      string
+	authorization string
+	gzip          bool
+
+	maxRetries      int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+}
+
+// newHTTPPosterWithSettings build the httpPoster from the common settings:
+// method, header_*, username, password, bearer_token, gzip, timeout, max_retries, retry_backoff, max_retry_backoff
+func newHTTPPosterWithSettings(url string, settings map[string]string) (*httpPoster, error) {
+	p := &httpPoster{
+		url:     url,
+		method:  http.MethodPost,
+		headers: map[string]string{},
+		gzip:    getBoolSetting(settings, "gzip", false),
+	}
+
+	if method, ok := settings["method"]; ok {
+		p.method = strings.ToUpper(method)
+	}
+
+	for k, v := range settings {
+		if strings.HasPrefix(k, httpHeaderSettingPrefix) {
+			p.headers[strings.TrimPrefix(k, httpHeaderSettingPrefix)] = v
+		}
+	}
+
+	username, hasUsername := settings["username"]
+	bearerToken, hasBearerToken := settings["bearer_token"]
+	if hasUsername && hasBearerToken {
+		return nil, errors.New("username and bearer_token should not be set together")
+	}
+	if hasUsername {
+		p.username = username
+		p.password = settings["password"]
+	}
+	if hasBearerToken {
+		p.authorization = "Bearer " + bearerToken
+	}
+
+	timeout, err := getDurationSetting(settings, "timeout", defaultHTTPTimeout)
+	if err != nil {
+		return nil, err
+	}
+	p.client = &http.Client{Timeout: timeout}
+
+	if p.maxRetries, err = getIntSetting(settings, "max_retries", defaultHTTPMaxRetries); err != nil {
+		return nil, err
+	}
+	if p.retryBackoff, err = getDurationSetting(settings, "retry_backoff", defaultHTTPRetryBackoff); err != nil {
+		return nil, err
+	}
+	if p.maxRetryBackoff, err = getDurationSetting(settings, "max_retry_backoff", defaultHTTPMaxRetryBackoff); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// post sends the body and returns the response body of the 2xx response,
+// retry with backoff if the request fails or the status code is 429/5xx, the Retry-After header is honored
+func (p *httpPoster) post(body []byte, contentType string) ([]byte, error) {
+	contentEncoding := ""
+	if p.gzip {
+		var b bytes.Buffer
+		w := gzip.NewWriter(&b)
+		if _, err := w.Write(body); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		body = b.Bytes()
+		contentEncoding = "gzip"
+	}
+
+	var err error
+	backoff := p.retryBackoff
+	for i := 0; i <= p.maxRetries; i++ {
+		var respBody []byte
+		var retryAfter time.Duration
+		respBody, retryAfter, err = p.do(body, contentType, contentEncoding)
+		if err == nil {
+			return respBody, nil
+		}
+		if retryAfter < 0 || i == p.maxRetries {
+			break
+		}
+
+		wait := backoff
+		if retryAfter > 0 {
+			wait = retryAfter
+		}
+		// the Retry-After is capped too, the sender should not be blocked too long
+		if wait > p.maxRetryBackoff {
+			wait = p.maxRetryBackoff
+		}
+		time.Sleep(wait)
+
+		backoff *= 2
+		if backoff > p.maxRetryBackoff {
+			backoff = p.maxRetryBackoff
+		}
+	}
+	return nil, err
+}
+
+// do sends the request once, the retryAfter will be negative if the error should not be retried
+func (p *httpPoster) do(body []byte, contentType, contentEncoding string) (respBody []byte, retryAfter time.Duration, err error) {
+	req, err := http.NewRequest(p.method, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, -1, err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	if p.username != "" {
+		req.SetBasicAuth(p.username, p.password)
+	}
+	if p.authorization != "" {
+		req.Header.Set("Authorization", p.authorization)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err = ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return respBody, 0, nil
+	}
+
+	err = fmt.Errorf("%s %s got status %d: %s", p.method, p.url, resp.StatusCode, truncateBody(respBody))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return nil, parseRetryAfter(resp.Header.Get("Retry-After")), err
+	}
+	return nil, -1, err
+}
+
+// parseRetryAfter parse the Retry-After header, in seconds or http date, returns 0 if not set or invalid
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+func truncateBody(body []byte) string {
+	if len(body) > 256 {
+		return string(body[:256]) + "..."
+	}
+	return string(body)
+}
@@ -0,0 +1,242 @@
+package hook
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPHook(t *testing.T) {
+	f := HTTPLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing url
+		{map[string]string{}, true},
+		// normal
+		{map[string]string{"url": "http://127.0.0.1:3100/logs"}, false},
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "encoding": "ndjson", "gzip": "true", "bearer_token": "abc"}, false},
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "username": "admin", "password": "123", "header_X-Scope-OrgID": "1"}, false},
+		// wrong encoding
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "encoding": "xml"}, true},
+		// basic and bearer auth together
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "username": "admin", "bearer_token": "abc"}, true},
+		// wrong timeout, retries, batch
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "timeout": "a"}, true},
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "max_retries": "a"}, true},
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "retry_backoff": "a"}, true},
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "max_retry_backoff": "a"}, true},
+		{map[string]string{"url": "http://127.0.0.1:3100/logs", "batch_max_count": "a"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestHTTPLogHookLevels(t *testing.T) {
+	h := HTTPLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), parseRetryAfter(""))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("abc"))
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3"))
+
+	d := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.True(t, d > 50*time.Second && d <= time.Minute)
+}
+
+type testHTTPRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newTestHTTPServer returns the status codes in order, then 200
+func newTestHTTPServer(statusCodes ...int) (*httptest.Server, func() []testHTTPRequest) {
+	var mu sync.Mutex
+	var requests []testHTTPRequest
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mu.Lock()
+		requests = append(requests, testHTTPRequest{header: r.Header, body: body})
+		n := len(requests)
+		mu.Unlock()
+
+		if n <= len(statusCodes) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(statusCodes[n-1])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	return server, func() []testHTTPRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestHTTPLogHookFireJSON(t *testing.T) {
+	server, requests := newTestHTTPServer()
+	defer server.Close()
+
+	h, err := HTTPLogHookBuilder{}.New("http", map[string]string{
+		"url":                  server.URL,
+		"username":             "admin",
+		"password":             "123",
+		"header_X-Scope-OrgID": "tenant1",
+		"batch_max_count":      "2",
+		"flush_interval":       "1h",
+	})
+	assert.NoError(t, err)
+
+	for _, msg := range []string{"a", "b", "c"} {
+		assert.NoError(t, h.Fire(&logrus.Entry{Message: msg, Data: logrus.Fields{}}))
+	}
+	h.(*HTTPLogHook).Flush()
+
+	reqs := requests()
+	assert.Len(t, reqs, 2)
+
+	var batch []map[string]interface{}
+	assert.NoError(t, json.Unmarshal(reqs[0].body, &batch))
+	assert.Len(t, batch, 2)
+	assert.Equal(t, "a", batch[0]["msg"])
+	assert.Equal(t, "b", batch[1]["msg"])
+
+	assert.NoError(t, json.Unmarshal(reqs[1].body, &batch))
+	assert.Len(t, batch, 1)
+	assert.Equal(t, "c", batch[0]["msg"])
+
+	assert.Equal(t, "application/json", reqs[0].header.Get("Content-Type"))
+	assert.Equal(t, "tenant1", reqs[0].header.Get("X-Scope-OrgID"))
+	assert.Equal(t, "Basic YWRtaW46MTIz", reqs[0].header.Get("Authorization"))
+}
+
+func TestHTTPLogHookFireNDJSONGzip(t *testing.T) {
+	server, requests := newTestHTTPServer()
+	defer server.Close()
+
+	h, err := HTTPLogHookBuilder{}.New("http", map[string]string{
+		"url":          server.URL,
+		"encoding":     "ndjson",
+		"gzip":         "true",
+		"bearer_token": "abc",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+
+	reqs := requests()
+	assert.Len(t, reqs, 1)
+	assert.Equal(t, "application/x-ndjson", reqs[0].header.Get("Content-Type"))
+	assert.Equal(t, "gzip", reqs[0].header.Get("Content-Encoding"))
+	assert.Equal(t, "Bearer abc", reqs[0].header.Get("Authorization"))
+
+	r, err := gzip.NewReader(bytes.NewReader(reqs[0].body))
+	assert.NoError(t, err)
+	scanner := bufio.NewScanner(r)
+	assert.True(t, scanner.Scan())
+
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+	assert.Equal(t, "hello", line["msg"])
+	assert.False(t, scanner.Scan())
+}
+
+func TestHTTPLogHookRetry(t *testing.T) {
+	// 503 and 429 will retry
+	server, requests := newTestHTTPServer(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+
+	h, err := HTTPLogHookBuilder{}.New("http", map[string]string{
+		"url":           server.URL,
+		"retry_backoff": "1ms",
+		"async_enable":  "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+	assert.Len(t, requests(), 3)
+
+	// 400 will not retry
+	server, requests = newTestHTTPServer(http.StatusBadRequest)
+	defer server.Close()
+
+	h, err = HTTPLogHookBuilder{}.New("http", map[string]string{
+		"url":           server.URL,
+		"retry_backoff": "1ms",
+		"async_enable":  "false",
+	})
+	assert.NoError(t, err)
+	assert.Error(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+	assert.Len(t, requests(), 1)
+
+	// give up after max retries
+	server, requests = newTestHTTPServer(500, 500, 500)
+	defer server.Close()
+
+	h, err = HTTPLogHookBuilder{}.New("http", map[string]string{
+		"url":           server.URL,
+		"max_retries":   "1",
+		"retry_backoff": "1ms",
+		"async_enable":  "false",
+	})
+	assert.NoError(t, err)
+	assert.Error(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+	assert.Len(t, requests(), 2)
+}
+
+func TestHTTPLogHookRetryAfterCapped(t *testing.T) {
+	var mu sync.Mutex
+	n := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		n++
+		first := n == 1
+		mu.Unlock()
+
+		if first {
+			w.Header().Set("Retry-After", "3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	h, err := HTTPLogHookBuilder{}.New("http", map[string]string{
+		"url":               server.URL,
+		"max_retry_backoff": "10ms",
+		"async_enable":      "false",
+	})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "hello", Data: logrus.Fields{}}))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, 2, n)
+}
@@ -0,0 +1,252 @@
+package hook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/xdg/scram"
+)
+
+type KafkaLogHookBuilder struct {
+	Formatter logrus.Formatter
+}
+
+// kafka: https://github.com/Shopify/sarama
+func (b KafkaLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	if err := validateRequiredHookSettings(name, settings, []string{"brokers", "topic"}); err != nil {
+		return nil, err
+	}
+
+	config, err := newKafkaConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := getBatchSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	brokers := strings.Split(settings["brokers"], ",")
+	for i := range brokers {
+		brokers[i] = strings.TrimSpace(brokers[i])
+	}
+
+	producer, err := sarama.NewSyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to KAFKA: %s", err)
+	}
+
+	formatter := b.Formatter
+	if formatter == nil {
+		formatter = &logrus.JSONFormatter{}
+	}
+
+	asyncEnable, asyncBufferSize, asyncBlock := getAsyncSettings(settings)
+
+	return newKafkaHook(producer, settings["topic"], settings["partition_key_field"], formatter,
+		batch, asyncEnable, asyncBufferSize, asyncBlock), nil
+}
+
+// newKafkaConfig build the sarama config from settings
+func newKafkaConfig(settings map[string]string) (*sarama.Config, error) {
+	config := sarama.NewConfig()
+	// the SyncProducer requires
+	config.Producer.Return.Successes = true
+
+	if clientID, ok := settings["client_id"]; ok {
+		config.ClientID = clientID
+	}
+
+	if version, ok := settings["version"]; ok {
+		v, err := sarama.ParseKafkaVersion(version)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid kafka version")
+		}
+		config.Version = v
+	}
+
+	if acks, ok := settings["required_acks"]; ok {
+		switch acks {
+		case "none", "0":
+			config.Producer.RequiredAcks = sarama.NoResponse
+		case "leader", "1":
+			config.Producer.RequiredAcks = sarama.WaitForLocal
+		case "all", "-1":
+			config.Producer.RequiredAcks = sarama.WaitForAll
+		default:
+			return nil, fmt.Errorf("unsupported kafka required_acks %s, should be none/leader/all", acks)
+		}
+	}
+
+	if compression, ok := settings["compression"]; ok {
+		switch compression {
+		case "none":
+			config.Producer.Compression = sarama.CompressionNone
+		case "gzip":
+			config.Producer.Compression = sarama.CompressionGZIP
+		case "snappy":
+			config.Producer.Compression = sarama.CompressionSnappy
+		case "lz4":
+			config.Producer.Compression = sarama.CompressionLZ4
+		case "zstd":
+			config.Producer.Compression = sarama.CompressionZSTD
+		default:
+			return nil, fmt.Errorf("unsupported kafka compression %s, should be none/gzip/snappy/lz4/zstd", compression)
+		}
+	}
+
+	var err error
+	if config.Producer.Timeout, err = getDurationSetting(settings, "timeout", config.Producer.Timeout); err != nil {
+		return nil, err
+	}
+	if config.Net.DialTimeout, err = getDurationSetting(settings, "dial_timeout", config.Net.DialTimeout); err != nil {
+		return nil, err
+	}
+	if config.Producer.Retry.Max, err = getIntSetting(settings, "max_retries", config.Producer.Retry.Max); err != nil {
+		return nil, err
+	}
+	if config.Producer.Retry.Backoff, err = getDurationSetting(settings, "retry_backoff", config.Producer.Retry.Backoff); err != nil {
+		return nil, err
+	}
+
+	if getBoolSetting(settings, "tls", false) {
+		tlsConfig, err := getTLSConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+	}
+
+	if mechanism, ok := settings["sasl_mechanism"]; ok {
+		config.Net.SASL.Enable = true
+		config.Net.SASL.User = settings["sasl_username"]
+		config.Net.SASL.Password = settings["sasl_password"]
+
+		switch mechanism {
+		case sarama.SASLTypePlaintext:
+			config.Net.SASL.Mechanism = sarama.SASLTypePlaintext
+		case sarama.SASLTypeSCRAMSHA256:
+			config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA256
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &kafkaSCRAMClient{HashGeneratorFcn: scram.HashGeneratorFcn(sha256.New)}
+			}
+		case sarama.SASLTypeSCRAMSHA512:
+			config.Net.SASL.Mechanism = sarama.SASLTypeSCRAMSHA512
+			config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+				return &kafkaSCRAMClient{HashGeneratorFcn: scram.HashGeneratorFcn(sha512.New)}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported kafka sasl_mechanism %s, should be PLAIN/SCRAM-SHA-256/SCRAM-SHA-512", mechanism)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid kafka config")
+	}
+	return config, nil
+}
+
+// KafkaLogHook publishes logs to a kafka topic
+type KafkaLogHook struct {
+	producer          sarama.SyncProducer
+	topic             string
+	partitionKeyField string
+	formatter         logrus.Formatter
+
+	batcher *batcher
+}
+
+func newKafkaHook(producer sarama.SyncProducer, topic, partitionKeyField string, formatter logrus.Formatter,
+	batch batchConfig, asyncEnable bool, asyncBufferSize int, asyncBlock bool) *KafkaLogHook {
+	hook := &KafkaLogHook{
+		producer:          producer,
+		topic:             topic,
+		partitionKeyField: partitionKeyField,
+		formatter:         formatter,
+	}
+	hook.batcher = newBatcher("kafka", batch, hook.encode, hook.send, asyncEnable, asyncBufferSize, asyncBlock)
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *KafkaLogHook) Fire(entry *logrus.Entry) error {
+	return h.batcher.Fire(entry)
+}
+
+// Flush sends all the buffered entries
+func (h *KafkaLogHook) Flush() {
+	h.batcher.Flush()
+}
+
+// Levels returns the available logging levels.
+func (h *KafkaLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *KafkaLogHook) encode(entry *logrus.Entry) ([]byte, error) {
+	msg, err := h.formatter.Format(entry)
+	if err != nil {
+		return nil, fmt.Errorf("error creating message for KAFKA: %s", err)
+	}
+	return bytes.TrimRight(msg, "\n"), nil
+}
+
+func (h *KafkaLogHook) send(items []batchItem) error {
+	msgs := make([]*sarama.ProducerMessage, 0, len(items))
+	for _, item := range items {
+		msgs = append(msgs, h.newProducerMessage(item))
+	}
+
+	if err := h.producer.SendMessages(msgs); err != nil {
+		return fmt.Errorf("error sending message to KAFKA: %s", err)
+	}
+	return nil
+}
+
+// newProducerMessage use the value of partition key field as the message key, so the entries with
+// the same key go to the same partition; without the key, the partition is chosen randomly
+func (h *KafkaLogHook) newProducerMessage(item batchItem) *sarama.ProducerMessage {
+	msg := &sarama.ProducerMessage{
+		Topic: h.topic,
+		Value: sarama.ByteEncoder(item.data),
+	}
+
+	if h.partitionKeyField != "" {
+		if v, ok := item.entry.Data[h.partitionKeyField]; ok {
+			msg.Key = sarama.StringEncoder(fmt.Sprint(v))
+		}
+	}
+	return msg
+}
+
+// kafkaSCRAMClient implements the sarama.SCRAMClient, see sarama/examples/sasl_scram_client
+type kafkaSCRAMClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (x *kafkaSCRAMClient) Begin(userName, password, authzID string) (err error) {
+	x.Client, err = x.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	x.ClientConversation = x.Client.NewConversation()
+	return nil
+}
+
+func (x *kafkaSCRAMClient) Step(challenge string) (response string, err error) {
+	return x.ClientConversation.Step(challenge)
+}
+
+func (x *kafkaSCRAMClient) Done() bool {
+	return x.ClientConversation.Done()
+}
@@ -0,0 +1,178 @@
+package hook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewKafkaConfig(t *testing.T) {
+	config, err := newKafkaConfig(map[string]string{})
+	assert.NoError(t, err)
+	assert.True(t, config.Producer.Return.Successes)
+	assert.Equal(t, sarama.WaitForLocal, config.Producer.RequiredAcks)
+
+	config, err = newKafkaConfig(map[string]string{
+		"client_id":      "myapp",
+		"version":        "2.1.0",
+		"required_acks":  "all",
+		"compression":    "snappy",
+		"timeout":        "5s",
+		"max_retries":    "5",
+		"retry_backoff":  "1s",
+		"tls":            "true",
+		"sasl_mechanism": "SCRAM-SHA-512",
+		"sasl_username":  "admin",
+		"sasl_password":  "123",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "myapp", config.ClientID)
+	assert.Equal(t, sarama.V2_1_0_0, config.Version)
+	assert.Equal(t, sarama.WaitForAll, config.Producer.RequiredAcks)
+	assert.Equal(t, sarama.CompressionSnappy, config.Producer.Compression)
+	assert.Equal(t, 5*time.Second, config.Producer.Timeout)
+	assert.Equal(t, 5, config.Producer.Retry.Max)
+	assert.Equal(t, time.Second, config.Producer.Retry.Backoff)
+	assert.True(t, config.Net.TLS.Enable)
+	assert.True(t, config.Net.SASL.Enable)
+	assert.NotNil(t, config.Net.SASL.SCRAMClientGeneratorFunc)
+
+	var data = []map[string]string{
+		{"version": "a"},
+		{"required_acks": "2"},
+		{"compression": "xz"},
+		{"timeout": "a"},
+		{"max_retries": "a"},
+		{"tls": "true", "tls_ca_file": "/not/exists"},
+		{"sasl_mechanism": "GSSAPI"},
+		// zstd requires kafka version >= 2.1.0
+		{"compression": "zstd"},
+	}
+	for _, d := range data {
+		_, err := newKafkaConfig(d)
+		assert.Error(t, err, d)
+	}
+}
+
+func TestKafkaSCRAMClient(t *testing.T) {
+	config, err := newKafkaConfig(map[string]string{"sasl_mechanism": "SCRAM-SHA-256", "sasl_username": "admin", "sasl_password": "123"})
+	assert.NoError(t, err)
+
+	client := config.Net.SASL.SCRAMClientGeneratorFunc()
+	assert.NoError(t, client.Begin("admin", "123", ""))
+	// the first message of client
+	response, err := client.Step("")
+	assert.NoError(t, err)
+	assert.Contains(t, response, "n=admin")
+	assert.False(t, client.Done())
+}
+
+func TestNewKafkaHook(t *testing.T) {
+	f := KafkaLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing brokers, topic
+		{map[string]string{}, true},
+		{map[string]string{"brokers": "127.0.0.1:9092"}, true},
+		// wrong config
+		{map[string]string{"brokers": "127.0.0.1:9092", "topic": "logs", "compression": "xz"}, true},
+		{map[string]string{"brokers": "127.0.0.1:9092", "topic": "logs", "batch_max_count": "a"}, true},
+		// no broker available
+		{map[string]string{"brokers": "127.1.1.1:9092", "topic": "logs", "dial_timeout": "100ms", "max_retries": "0"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestKafkaLogHookLevels(t *testing.T) {
+	h := KafkaLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+func TestKafkaLogHookNewProducerMessage(t *testing.T) {
+	h := &KafkaLogHook{topic: "logs", partitionKeyField: "request_id"}
+
+	msg := h.newProducerMessage(batchItem{entry: &logrus.Entry{Data: logrus.Fields{"request_id": "abc"}}, data: []byte("hello")})
+	assert.Equal(t, "logs", msg.Topic)
+	assert.Equal(t, sarama.StringEncoder("abc"), msg.Key)
+	assert.Equal(t, sarama.ByteEncoder("hello"), msg.Value)
+
+	msg = h.newProducerMessage(batchItem{entry: &logrus.Entry{Data: logrus.Fields{"request_id": 123}}, data: []byte("hello")})
+	assert.Equal(t, sarama.StringEncoder("123"), msg.Key)
+
+	// without the field
+	msg = h.newProducerMessage(batchItem{entry: &logrus.Entry{Data: logrus.Fields{}}, data: []byte("hello")})
+	assert.Nil(t, msg.Key)
+}
+
+func TestKafkaLogHookFireMockProducer(t *testing.T) {
+	producer := mocks.NewSyncProducer(t, nil)
+	producer.ExpectSendMessageWithCheckerFunctionAndSucceed(func(val []byte) error {
+		assert.Contains(t, string(val), `"msg":"a"`)
+		return nil
+	})
+	producer.ExpectSendMessageAndSucceed()
+
+	h := newKafkaHook(producer, "logs", "", &logrus.JSONFormatter{},
+		batchConfig{maxCount: 2, maxBytes: defaultBatchMaxBytes, flushInterval: time.Hour}, true, 10, true)
+
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "a", Data: logrus.Fields{}}))
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "b", Data: logrus.Fields{}}))
+	h.Flush()
+
+	assert.NoError(t, producer.Close())
+}
+
+func TestKafkaLogHookFireMockBroker(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	metadata := sarama.NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader("logs", 0, broker.BrokerID())
+	// the produce request is v3 with the default kafka version 1.0.0
+	produce := sarama.NewMockProduceResponse(t).SetVersion(3)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		"ProduceRequest":  produce,
+	})
+
+	h, err := KafkaLogHookBuilder{}.New("kafka", map[string]string{
+		"brokers":             broker.Addr(),
+		"topic":               "logs",
+		"partition_key_field": "request_id",
+		"max_retries":         "0",
+		"async_enable":        "false",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, h.Fire(&logrus.Entry{Message: "a", Data: logrus.Fields{"request_id": "abc"}}))
+
+	var produceRequests int
+	for _, rr := range broker.History() {
+		if _, ok := rr.Request.(*sarama.ProduceRequest); ok {
+			produceRequests++
+		}
+	}
+	assert.Equal(t, 1, produceRequests)
+
+	// the broker returns error
+	produce.SetError("logs", 0, sarama.ErrNotEnoughReplicas)
+	assert.Error(t, h.Fire(&logrus.Entry{Message: "b", Data: logrus.Fields{}}))
+}
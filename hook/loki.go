@@ -0,0 +1,352 @@
+package hook
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	LokiEncodingProtobuf = "protobuf"
+	LokiEncodingJSON     = "json"
+
+	lokiPushPath = "/loki/api/v1/push"
+	// the settings with this prefix will be the static labels, e.g. `label_job: myProject`
+	lokiLabelSettingPrefix = "label_"
+	// the label of entry.Level
+	lokiLevelLabel = "level"
+)
+
+type LokiLogHookBuilder struct {
+}
+
+// loki: push the entries to loki, grouped into streams by the labels
+// https://grafana.com/docs/loki/latest/api/#push-log-entries-to-loki
+func (b LokiLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	if err := validateRequiredHookSettings(name, settings, []string{"url"}); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(settings["url"])
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid loki url %s, should be like http://127.0.0.1:3100", settings["url"])
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + lokiPushPath
+
+	encoding, ok := settings["encoding"]
+	if !ok {
+		encoding = LokiEncodingProtobuf
+	}
+	if encoding != LokiEncodingProtobuf && encoding != LokiEncodingJSON {
+		return nil, fmt.Errorf("unsupported loki encoding %s, should be protobuf or json", encoding)
+	}
+
+	poster, err := newHTTPPosterWithSettings(u.String(), settings)
+	if err != nil {
+		return nil, err
+	}
+	if poster.gzip && encoding == LokiEncodingProtobuf {
+		return nil, errors.New("gzip is only supported by json encoding, protobuf is compressed by snappy")
+	}
+	if tenantID, ok := settings["tenant_id"]; ok {
+		poster.headers["X-Scope-OrgID"] = tenantID
+	}
+
+	// the fields as labels, default is level
+	labelFields := []string{lokiLevelLabel}
+	if labels, ok := settings["labels"]; ok {
+		labelFields = nil
+		for _, field := range strings.Split(labels, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				labelFields = append(labelFields, field)
+			}
+		}
+	}
+
+	staticLabels := map[string]string{}
+	for k, v := range settings {
+		if strings.HasPrefix(k, lokiLabelSettingPrefix) {
+			staticLabels[lokiLabelName(strings.TrimPrefix(k, lokiLabelSettingPrefix))] = v
+		}
+	}
+
+	if len(labelFields) == 0 && len(staticLabels) == 0 {
+		return nil, errors.New("loki requires at least one label, set labels or label_{name}")
+	}
+
+	batch, err := getBatchSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	config := LokiHookConfig{
+		Encoding:     encoding,
+		LabelFields:  labelFields,
+		StaticLabels: staticLabels,
+
+		poster: poster,
+		batch:  batch,
+	}
+	config.asyncEnable, config.asyncBufferSize, config.asyncBlock = getAsyncSettings(settings)
+
+	return newLokiHook(config), nil
+}
+
+// LokiHookConfig stores configuration needed to setup the hook
+type LokiHookConfig struct {
+	// Encoding is protobuf(snappy compressed) or json
+	Encoding string
+	// LabelFields are the fields used as labels, removed from the line; `level` is the level of entry
+	LabelFields  []string
+	StaticLabels map[string]string
+
+	poster *httpPoster
+	batch  batchConfig
+
+	asyncEnable     bool
+	asyncBufferSize int
+	asyncBlock      bool
+}
+
+// LokiLogHook pushes logs to loki in batches
+type LokiLogHook struct {
+	poster       *httpPoster
+	encoding     string
+	labelFields  map[string]bool
+	staticLabels map[string]string
+
+	batcher *batcher
+}
+
+func newLokiHook(config LokiHookConfig) *LokiLogHook {
+	hook := &LokiLogHook{
+		poster:       config.poster,
+		encoding:     config.Encoding,
+		labelFields:  make(map[string]bool, len(config.LabelFields)),
+		staticLabels: config.StaticLabels,
+	}
+	for _, field := range config.LabelFields {
+		hook.labelFields[field] = true
+	}
+
+	hook.batcher = newBatcher("loki", config.batch, hook.encode, hook.send,
+		config.asyncEnable, config.asyncBufferSize, config.asyncBlock)
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *LokiLogHook) Fire(entry *logrus.Entry) error {
+	return h.batcher.Fire(entry)
+}
+
+// Flush sends all the buffered entries
+func (h *LokiLogHook) Flush() {
+	h.batcher.Flush()
+}
+
+// Levels returns the available logging levels.
+func (h *LokiLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// encode renders the line, a json with the message and the fields which are not labels
+func (h *LokiLogHook) encode(entry *logrus.Entry) ([]byte, error) {
+	m := make(map[string]interface{}, len(entry.Data)+2)
+	m["msg"] = entry.Message
+	if !h.labelFields[lokiLevelLabel] {
+		m["level"] = entry.Level.String()
+	}
+	for k, v := range entry.Data {
+		if h.labelFields[k] {
+			continue
+		}
+		// not to silently overwrite the msg and level, same as logrus
+		if _, ok := m[k]; ok {
+			k = "fields." + k
+		}
+		switch v := v.(type) {
+		case error:
+			m[k] = v.Error()
+		default:
+			m[k] = v
+		}
+	}
+
+	line, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("error creating message for LOKI: %s", err)
+	}
+	return line, nil
+}
+
+// labels returns the labels of the entry, in the format of `{app="api", level="info"}`
+func (h *LokiLogHook) labels(entry *logrus.Entry) (string, map[string]string) {
+	labels := make(map[string]string, len(h.labelFields)+len(h.staticLabels))
+	for k, v := range h.staticLabels {
+		labels[k] = v
+	}
+	for field := range h.labelFields {
+		if field == lokiLevelLabel {
+			labels[lokiLevelLabel] = entry.Level.String()
+			continue
+		}
+		// the entry without the field will not have the label
+		if v, ok := entry.Data[field]; ok {
+			labels[lokiLabelName(field)] = fmt.Sprint(v)
+		}
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String(), labels
+}
+
+// lokiStream is the entries with the same labels
+type lokiStream struct {
+	labels map[string]string
+	items  []batchItem
+}
+
+func (h *LokiLogHook) send(items []batchItem) error {
+	// group into streams, keep the order of first appearance
+	var keys []string
+	streams := map[string]*lokiStream{}
+	for _, item := range items {
+		key, labels := h.labels(item.entry)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{labels: labels}
+			streams[key] = stream
+			keys = append(keys, key)
+		}
+		stream.items = append(stream.items, item)
+	}
+
+	var body []byte
+	var contentType string
+	var err error
+	if h.encoding == LokiEncodingJSON {
+		contentType = "application/json"
+		body, err = encodeLokiJSON(keys, streams)
+		if err != nil {
+			return fmt.Errorf("error creating message for LOKI: %s", err)
+		}
+	} else {
+		contentType = "application/x-protobuf"
+		body = snappy.Encode(nil, encodeLokiProtobuf(keys, streams))
+	}
+
+	if _, err := h.poster.post(body, contentType); err != nil {
+		return fmt.Errorf("error sending message to LOKI: %s", err)
+	}
+	return nil
+}
+
+// encodeLokiJSON renders `{"streams": [{"stream": {labels}, "values": [["unix nano", "line"]]}]}`
+func encodeLokiJSON(keys []string, streams map[string]*lokiStream) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString(`{"streams":[`)
+	for i, key := range keys {
+		stream := streams[key]
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		labels, err := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(stream.labels)
+		if err != nil {
+			return nil, err
+		}
+		b.WriteString(`{"stream":`)
+		b.Write(labels)
+		b.WriteString(`,"values":[`)
+		for j, item := range stream.items {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+			line, err := jsoniter.Marshal(string(item.data))
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(`["`)
+			b.WriteString(strconv.FormatInt(item.entry.Time.UnixNano(), 10))
+			b.WriteString(`",`)
+			b.Write(line)
+			b.WriteByte(']')
+		}
+		b.WriteString(`]}`)
+	}
+	b.WriteString(`]}`)
+	return b.Bytes(), nil
+}
+
+// encodeLokiProtobuf renders the logproto.PushRequest
+// https://github.com/grafana/loki/blob/main/pkg/logproto/logproto.proto
+func encodeLokiProtobuf(keys []string, streams map[string]*lokiStream) []byte {
+	var request []byte
+	for _, key := range keys {
+		// StreamAdapter{labels = 1, entries = 2}
+		var stream []byte
+		stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+		stream = protowire.AppendString(stream, key)
+
+		for _, item := range streams[key].items {
+			// google.protobuf.Timestamp{seconds = 1, nanos = 2}
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(item.entry.Time.Unix()))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(item.entry.Time.Nanosecond()))
+
+			// EntryAdapter{timestamp = 1, line = 2}
+			var entry []byte
+			entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, ts)
+			entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+			entry = protowire.AppendBytes(entry, item.data)
+
+			stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+			stream = protowire.AppendBytes(stream, entry)
+		}
+
+		// PushRequest{streams = 1}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, stream)
+	}
+	return request
+}
+
+// lokiLabelName replaces the invalid characters with `_`, the label name should match `[a-zA-Z_][a-zA-Z0-9_]*`
+func lokiLabelName(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		b[i] = '_'
+	}
+	return string(b)
+}
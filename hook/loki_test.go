@@ -0,0 +1,199 @@
+package hook
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestNewLokiHook(t *testing.T) {
+	f := LokiLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing url
+		{map[string]string{}, true},
+		// normal
+		{map[string]string{"url": "http://127.0.0.1:3100"}, false},
+		{map[string]string{"url": "http://127.0.0.1:3100", "labels": "app, env, level", "tenant_id": "tenant1"}, false},
+		{map[string]string{"url": "http://127.0.0.1:3100", "labels": "", "label_job": "myProject", "encoding": "json", "gzip": "true"}, false},
+		// wrong url
+		{map[string]string{"url": "127.0.0.1:3100"}, true},
+		// wrong encoding
+		{map[string]string{"url": "http://127.0.0.1:3100", "encoding": "xml"}, true},
+		// gzip with protobuf
+		{map[string]string{"url": "http://127.0.0.1:3100", "gzip": "true"}, true},
+		// no labels
+		{map[string]string{"url": "http://127.0.0.1:3100", "labels": ""}, true},
+		// wrong batch
+		{map[string]string{"url": "http://127.0.0.1:3100", "batch_max_count": "a"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestLokiLogHookLevels(t *testing.T) {
+	h := LokiLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+func TestLokiLabelName(t *testing.T) {
+	assert.Equal(t, "app", lokiLabelName("app"))
+	assert.Equal(t, "request_id", lokiLabelName("request-id"))
+	assert.Equal(t, "_a", lokiLabelName("1a"))
+	assert.Equal(t, "a1_b", lokiLabelName("a1.b"))
+}
+
+type testLokiEntry struct {
+	ts   time.Time
+	line string
+}
+
+type testLokiStream struct {
+	labels  string
+	entries []testLokiEntry
+}
+
+// decodeLokiPushRequest decodes the snappy compressed logproto.PushRequest
+func decodeLokiPushRequest(t *testing.T, body []byte) []testLokiStream {
+	request, err := snappy.Decode(nil, body)
+	assert.NoError(t, err)
+
+	fields := func(b []byte) map[protowire.Number][][]byte {
+		m := map[protowire.Number][][]byte{}
+		for len(b) > 0 {
+			num, typ, n := protowire.ConsumeTag(b)
+			assert.True(t, n > 0)
+			b = b[n:]
+			switch typ {
+			case protowire.BytesType:
+				v, n := protowire.ConsumeBytes(b)
+				m[num] = append(m[num], v)
+				b = b[n:]
+			case protowire.VarintType:
+				v, n := protowire.ConsumeVarint(b)
+				m[num] = append(m[num], protowire.AppendVarint(nil, v))
+				b = b[n:]
+			default:
+				t.Fatalf("unexpected wire type %d", typ)
+			}
+		}
+		return m
+	}
+	varint := func(b []byte) int64 {
+		v, _ := protowire.ConsumeVarint(b)
+		return int64(v)
+	}
+
+	var streams []testLokiStream
+	for _, s := range fields(request)[1] {
+		sf := fields(s)
+		stream := testLokiStream{labels: string(sf[1][0])}
+		for _, e := range sf[2] {
+			ef := fields(e)
+			ts := fields(ef[1][0])
+			stream.entries = append(stream.entries, testLokiEntry{
+				ts:   time.Unix(varint(ts[1][0]), varint(ts[2][0])),
+				line: string(ef[2][0]),
+			})
+		}
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+func TestLokiLogHookFireProtobuf(t *testing.T) {
+	server, requests := newTestHTTPServer()
+	defer server.Close()
+
+	h, err := LokiLogHookBuilder{}.New("loki", map[string]string{
+		"url":             server.URL,
+		"labels":          "app,level",
+		"label_job":       "myProject",
+		"tenant_id":       "tenant1",
+		"batch_max_count": "3",
+		"flush_interval":  "1h",
+	})
+	assert.NoError(t, err)
+
+	ts := time.Date(2020, 5, 1, 10, 0, 0, 123, time.UTC)
+	entries := []*logrus.Entry{
+		{Message: "a", Level: logrus.InfoLevel, Time: ts, Data: logrus.Fields{"app": "api", "id": 1}},
+		{Message: "b", Level: logrus.ErrorLevel, Time: ts, Data: logrus.Fields{"app": "api", "error": errors.New("oops")}},
+		{Message: "c", Level: logrus.InfoLevel, Time: ts.Add(time.Second), Data: logrus.Fields{"app": "api"}},
+	}
+	for _, entry := range entries {
+		assert.NoError(t, h.Fire(entry))
+	}
+	h.(*LokiLogHook).Flush()
+
+	reqs := requests()
+	assert.Len(t, reqs, 1)
+	assert.Equal(t, "application/x-protobuf", reqs[0].header.Get("Content-Type"))
+	assert.Equal(t, "tenant1", reqs[0].header.Get("X-Scope-OrgID"))
+
+	streams := decodeLokiPushRequest(t, reqs[0].body)
+	assert.Len(t, streams, 2)
+
+	assert.Equal(t, `{app="api", job="myProject", level="info"}`, streams[0].labels)
+	assert.Len(t, streams[0].entries, 2)
+	assert.True(t, ts.Equal(streams[0].entries[0].ts))
+	assert.Equal(t, `{"id":1,"msg":"a"}`, streams[0].entries[0].line)
+	assert.Equal(t, `{"msg":"c"}`, streams[0].entries[1].line)
+
+	assert.Equal(t, `{app="api", job="myProject", level="error"}`, streams[1].labels)
+	assert.Equal(t, `{"error":"oops","msg":"b"}`, streams[1].entries[0].line)
+}
+
+func TestLokiLogHookFireJSON(t *testing.T) {
+	server, requests := newTestHTTPServer()
+	defer server.Close()
+
+	h, err := LokiLogHookBuilder{}.New("loki", map[string]string{
+		"url":          server.URL,
+		"encoding":     "json",
+		"labels":       "env",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+
+	ts := time.Unix(1588327200, 5)
+	assert.NoError(t, h.Fire(&logrus.Entry{
+		Message: "hello",
+		Level:   logrus.WarnLevel,
+		Time:    ts,
+		Data:    logrus.Fields{"env": "prod", "id": "x", "msg": "m", "level": 1},
+	}))
+
+	reqs := requests()
+	assert.Len(t, reqs, 1)
+	assert.Equal(t, "application/json", reqs[0].header.Get("Content-Type"))
+
+	var body struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][]string        `json:"values"`
+		} `json:"streams"`
+	}
+	assert.NoError(t, json.Unmarshal(reqs[0].body, &body))
+	assert.Len(t, body.Streams, 1)
+	assert.Equal(t, map[string]string{"env": "prod"}, body.Streams[0].Stream)
+	assert.Equal(t, [][]string{{"1588327200000000005", `{"fields.level":1,"fields.msg":"m","id":"x","level":"warning","msg":"hello"}`}}, body.Streams[0].Values)
+}
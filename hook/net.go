@@ -0,0 +1,110 @@
+package hook
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+type NetLogHookBuilder struct {
+	Formatter logrus.Formatter
+}
+
+// net: stream the formatted entries to tcp/udp/unix socket, e.g. the tcp input of fluent-bit/vector/logstash
+func (b NetLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	if err := validateRequiredHookSettings(name, settings, []string{"address"}); err != nil {
+		return nil, err
+	}
+
+	network, ok := settings["network"]
+	if !ok {
+		network = "tcp"
+	}
+	switch network {
+	case "tcp", "udp", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unsupported net network %s, should be one of tcp/udp/unix/unixgram", network)
+	}
+
+	framing, ok := settings["framing"]
+	if !ok {
+		framing = FramingNewline
+	}
+	switch framing {
+	case FramingNewline, FramingOctetCounting, FramingLengthPrefixed:
+	default:
+		return nil, fmt.Errorf("unsupported net framing %s, should be one of newline/octet_counting/length_prefixed", framing)
+	}
+
+	useTLS := getBoolSetting(settings, "tls", false)
+	if useTLS && network != "tcp" {
+		return nil, fmt.Errorf("tls is only supported by tcp, not %s", network)
+	}
+
+	writer, err := newNetWriterWithSettings(network, settings["address"], useTLS, settings)
+	if err != nil {
+		return nil, err
+	}
+
+	formatter := b.Formatter
+	if formatter == nil {
+		formatter = &logrus.JSONFormatter{}
+	}
+
+	asyncEnable, asyncBufferSize, asyncBlock := getAsyncSettings(settings)
+
+	return newNetHook(writer, framing, formatter, asyncEnable, asyncBufferSize, asyncBlock), nil
+}
+
+// NetLogHook sends the formatted logs to a socket
+type NetLogHook struct {
+	writer    *netWriter
+	framing   string
+	formatter logrus.Formatter
+
+	async *asyncSender
+}
+
+func newNetHook(writer *netWriter, framing string, formatter logrus.Formatter,
+	asyncEnable bool, asyncBufferSize int, asyncBlock bool) *NetLogHook {
+	hook := &NetLogHook{
+		writer:    writer,
+		framing:   framing,
+		formatter: formatter,
+	}
+
+	if asyncEnable {
+		hook.async = newAsyncSender("net", asyncBufferSize, asyncBlock, hook.send)
+	}
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *NetLogHook) Fire(entry *logrus.Entry) error {
+	if h.async != nil {
+		return h.async.Fire(entry)
+	}
+	return h.send(entry)
+}
+
+func (h *NetLogHook) send(entry *logrus.Entry) error {
+	msg, err := h.formatter.Format(entry)
+	if err != nil {
+		return fmt.Errorf("error creating message for NET: %s", err)
+	}
+
+	// datagram keeps the message boundary, no need to frame
+	if h.writer.isStream() {
+		msg = frame(h.framing, msg)
+	}
+
+	if _, err := h.writer.Write(msg); err != nil {
+		return fmt.Errorf("error sending message to NET: %s", err)
+	}
+	return nil
+}
+
+// Levels returns the available logging levels.
+func (h *NetLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
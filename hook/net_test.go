@@ -0,0 +1,242 @@
+package hook
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewNetHook(t *testing.T) {
+	f := NetLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing address
+		{map[string]string{}, true},
+		// normal, will dial lazily
+		{map[string]string{"address": "127.0.0.1:5170"}, false},
+		{map[string]string{"address": "127.0.0.1:5170", "network": "udp"}, false},
+		{map[string]string{"address": "127.0.0.1:5170", "framing": "length_prefixed", "max_retries": "3", "retry_backoff": "1s"}, false},
+		{map[string]string{"address": "127.0.0.1:5170", "tls": "true", "tls_insecure_skip_verify": "true"}, false},
+		// wrong network
+		{map[string]string{"address": "127.0.0.1:5170", "network": "http"}, true},
+		// wrong framing
+		{map[string]string{"address": "127.0.0.1:5170", "framing": "xml"}, true},
+		// tls over udp
+		{map[string]string{"address": "127.0.0.1:5170", "network": "udp", "tls": "true"}, true},
+		// wrong retry settings
+		{map[string]string{"address": "127.0.0.1:5170", "max_retries": "a"}, true},
+		{map[string]string{"address": "127.0.0.1:5170", "retry_backoff": "a"}, true},
+		{map[string]string{"address": "127.0.0.1:5170", "max_retry_backoff": "a"}, true},
+		{map[string]string{"address": "127.0.0.1:5170", "dial_timeout": "a"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestNetLogHookLevels(t *testing.T) {
+	h := NetLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+func TestFrame(t *testing.T) {
+	assert.Equal(t, "hello\n", string(frame(FramingNewline, []byte("hello\n"))))
+	assert.Equal(t, "hello\n", string(frame(FramingNewline, []byte("hello"))))
+	assert.Equal(t, "5 hello", string(frame(FramingOctetCounting, []byte("hello\n"))))
+	assert.Equal(t, "\x00\x00\x00\x05hello", string(frame(FramingLengthPrefixed, []byte("hello\n"))))
+}
+
+func newTestNetEntry() *logrus.Entry {
+	return &logrus.Entry{
+		Message: "hello",
+		Level:   logrus.InfoLevel,
+		Time:    time.Now(),
+		Data:    logrus.Fields{"a": 1},
+	}
+}
+
+// acceptOne accept one connection from the listener and read one message
+func acceptOne(ln net.Listener, read func(reader *bufio.Reader) (string, error)) <-chan string {
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		msg, err := read(bufio.NewReader(conn))
+		if err == nil {
+			received <- msg
+		}
+	}()
+	return received
+}
+
+func readLine(reader *bufio.Reader) (string, error) {
+	return reader.ReadString('\n')
+}
+
+func readLengthPrefixed(reader *bufio.Reader) (string, error) {
+	var length uint32
+	if err := binary.Read(reader, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	msg := make([]byte, length)
+	_, err := io.ReadFull(reader, msg)
+	return string(msg), err
+}
+
+func assertNetMessage(t *testing.T, received <-chan string) string {
+	select {
+	case msg := <-received:
+		var data map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(msg), &data))
+		assert.Equal(t, "hello", data["msg"])
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+	return ""
+}
+
+func TestNetLogHookFireTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	// newline
+	received := acceptOne(ln, readLine)
+	h, err := NetLogHookBuilder{}.New("net", map[string]string{"address": ln.Addr().String(), "async_enable": "false"})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(newTestNetEntry()))
+	msg := assertNetMessage(t, received)
+	assert.Equal(t, byte('\n'), msg[len(msg)-1])
+
+	// length prefixed, in async mode
+	received = acceptOne(ln, readLengthPrefixed)
+	h, err = NetLogHookBuilder{}.New("net", map[string]string{
+		"address":      ln.Addr().String(),
+		"framing":      "length_prefixed",
+		"async_enable": "true",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(newTestNetEntry()))
+	assertNetMessage(t, received)
+}
+
+func TestNetLogHookFireUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	h, err := NetLogHookBuilder{}.New("net", map[string]string{
+		"address":      conn.LocalAddr().String(),
+		"network":      "udp",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(newTestNetEntry()))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+
+	var data map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf[:n], &data))
+	assert.Equal(t, "hello", data["msg"])
+}
+
+func TestNetLogHookFireUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging-net")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "net.sock")
+	ln, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	received := acceptOne(ln, func(reader *bufio.Reader) (string, error) {
+		// octet counting
+		prefix, err := reader.ReadString(' ')
+		if err != nil {
+			return "", err
+		}
+		length, _ := strconv.Atoi(strings.TrimSpace(prefix))
+		msg := make([]byte, length)
+		_, err = io.ReadFull(reader, msg)
+		return string(msg), err
+	})
+
+	h, err := NetLogHookBuilder{}.New("net", map[string]string{
+		"address":      path,
+		"network":      "unix",
+		"framing":      "octet_counting",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(newTestNetEntry()))
+	assertNetMessage(t, received)
+}
+
+func TestNetLogHookFireTLS(t *testing.T) {
+	certFile, keyFile := newTestTLSFiles(t)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	received := acceptOne(ln, readLine)
+	h, err := NetLogHookBuilder{}.New("net", map[string]string{
+		"address":      ln.Addr().String(),
+		"tls":          "true",
+		"tls_ca_file":  certFile,
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(newTestNetEntry()))
+	assertNetMessage(t, received)
+}
+
+func TestNetLogHookFireFail(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	h, err := NetLogHookBuilder{}.New("net", map[string]string{
+		"address":       addr,
+		"max_retries":   "2",
+		"retry_backoff": "1ms",
+		"async_enable":  "false",
+	})
+	assert.NoError(t, err)
+	assert.Error(t, h.Fire(newTestNetEntry()))
+}
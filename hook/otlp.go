@@ -0,0 +1,368 @@
+package hook
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	OTLPProtocolHTTP = "http/protobuf"
+	OTLPProtocolGRPC = "grpc"
+
+	otlpHTTPLogsPath         = "/v1/logs"
+	otlpInstrumentationScope = "github.com/wklken/logging-go"
+)
+
+type OTLPLogHookBuilder struct {
+}
+
+// otlp: export the entries as OpenTelemetry LogRecords, over OTLP/HTTP(protobuf) or OTLP/gRPC
+// https://github.com/open-telemetry/opentelemetry-specification/blob/main/specification/protocol/otlp.md
+func (b OTLPLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	if err := validateRequiredHookSettings(name, settings, []string{"endpoint"}); err != nil {
+		return nil, err
+	}
+
+	protocol, ok := settings["protocol"]
+	if !ok {
+		protocol = OTLPProtocolHTTP
+	}
+
+	var exporter otlpExporter
+	var err error
+	switch protocol {
+	case OTLPProtocolHTTP:
+		exporter, err = newOTLPHTTPExporter(settings["endpoint"], settings)
+	case OTLPProtocolGRPC:
+		exporter, err = newOTLPGRPCExporter(settings["endpoint"], settings)
+	default:
+		return nil, fmt.Errorf("unsupported otlp protocol %s, should be http/protobuf or grpc", protocol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	batch, err := getBatchSettings(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	serviceName, ok := settings["service_name"]
+	if !ok {
+		serviceName = os.Args[0]
+	}
+
+	asyncEnable, asyncBufferSize, asyncBlock := getAsyncSettings(settings)
+
+	return newOTLPHook(exporter, newOTLPResource(serviceName), batch, asyncEnable, asyncBufferSize, asyncBlock), nil
+}
+
+// OTLPLogHook exports logs to the OpenTelemetry collector
+type OTLPLogHook struct {
+	exporter otlpExporter
+	// resource is the Resource of all the LogRecords
+	resource *resourcepb.Resource
+
+	batcher *batcher
+}
+
+func newOTLPHook(exporter otlpExporter, resource *resourcepb.Resource, batch batchConfig,
+	asyncEnable bool, asyncBufferSize int, asyncBlock bool) *OTLPLogHook {
+	hook := &OTLPLogHook{
+		exporter: exporter,
+		resource: resource,
+	}
+	hook.batcher = newValueBatcher("otlp", batch, hook.convert, hook.send, asyncEnable, asyncBufferSize, asyncBlock)
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *OTLPLogHook) Fire(entry *logrus.Entry) error {
+	return h.batcher.Fire(entry)
+}
+
+// Flush sends all the buffered entries
+func (h *OTLPLogHook) Flush() {
+	h.batcher.Flush()
+}
+
+// Levels returns the available logging levels.
+func (h *OTLPLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// convert converts the entry into the LogRecord, with the encoded size counted into the batch bytes
+func (h *OTLPLogHook) convert(entry *logrus.Entry) (interface{}, int, error) {
+	record := newOTLPLogRecord(entry)
+	return record, proto.Size(record), nil
+}
+
+// send builds the ExportLogsServiceRequest with the LogRecords
+func (h *OTLPLogHook) send(items []batchItem) error {
+	records := make([]*logspb.LogRecord, 0, len(items))
+	for _, item := range items {
+		records = append(records, item.value.(*logspb.LogRecord))
+	}
+
+	request := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: h.resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: otlpInstrumentationScope},
+				LogRecords: records,
+			}},
+		}},
+	}
+	if err := h.exporter.export(request); err != nil {
+		return fmt.Errorf("error sending message to OTLP: %s", err)
+	}
+	return nil
+}
+
+func newOTLPResource(serviceName string) *resourcepb.Resource {
+	attributes := []*commonpb.KeyValue{
+		{Key: "service.name", Value: newOTLPAnyValue(serviceName)},
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		attributes = append(attributes, &commonpb.KeyValue{Key: "host.name", Value: newOTLPAnyValue(hostname)})
+	}
+	return &resourcepb.Resource{Attributes: attributes}
+}
+
+// newOTLPLogRecord converts the entry, the fields to attributes, and the trace/span id from the entry.Context
+func newOTLPLogRecord(entry *logrus.Entry) *logspb.LogRecord {
+	record := &logspb.LogRecord{
+		TimeUnixNano:   uint64(entry.Time.UnixNano()),
+		SeverityNumber: otlpSeverityNumber(entry.Level),
+		SeverityText:   entry.Level.String(),
+		Body:           newOTLPAnyValue(entry.Message),
+		Attributes:     make([]*commonpb.KeyValue, 0, len(entry.Data)),
+	}
+
+	for _, k := range sortedKeys(entry.Data) {
+		record.Attributes = append(record.Attributes, &commonpb.KeyValue{Key: k, Value: newOTLPAnyValue(entry.Data[k])})
+	}
+
+	if entry.Context != nil {
+		spanContext := trace.SpanContextFromContext(entry.Context)
+		if spanContext.IsValid() {
+			traceID := spanContext.TraceID()
+			spanID := spanContext.SpanID()
+			record.TraceId = traceID[:]
+			record.SpanId = spanID[:]
+			record.Flags = uint32(spanContext.TraceFlags())
+		}
+	}
+	return record
+}
+
+// otlpSeverityNumber maps the logrus level to the SeverityNumber
+func otlpSeverityNumber(level logrus.Level) logspb.SeverityNumber {
+	switch level {
+	case logrus.TraceLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_TRACE
+	case logrus.DebugLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case logrus.InfoLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case logrus.WarnLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case logrus.ErrorLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case logrus.FatalLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	default:
+		// panic is more severe than fatal
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4
+	}
+}
+
+// newOTLPAnyValue converts the field value, the slices and maps will be array and kvlist,
+// and the unsupported types will be `%v`
+func newOTLPAnyValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case nil:
+		return &commonpb.AnyValue{}
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case []byte:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BytesValue{BytesValue: v}}
+	case error:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Error()}}
+	case time.Time:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.Format(time.RFC3339Nano)}}
+	case fmt.Stringer:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v.String()}}
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: rv.Int()}}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: int64(rv.Uint())}}
+	case reflect.Float32, reflect.Float64:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: rv.Float()}}
+	case reflect.Slice, reflect.Array:
+		values := make([]*commonpb.AnyValue, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, newOTLPAnyValue(rv.Index(i).Interface()))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{Values: values}}}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			data := make(logrus.Fields, rv.Len())
+			for _, k := range rv.MapKeys() {
+				data[k.String()] = rv.MapIndex(k).Interface()
+			}
+			values := make([]*commonpb.KeyValue, 0, len(data))
+			for _, k := range sortedKeys(data) {
+				values = append(values, &commonpb.KeyValue{Key: k, Value: newOTLPAnyValue(data[k])})
+			}
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{Values: values}}}
+		}
+	}
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprintf("%v", v)}}
+}
+
+// otlpExporter sends the ExportLogsServiceRequest
+type otlpExporter interface {
+	export(request *collogspb.ExportLogsServiceRequest) error
+}
+
+// otlpHTTPExporter posts the request to the `/v1/logs`, with the http settings, see httpPoster
+type otlpHTTPExporter struct {
+	poster *httpPoster
+}
+
+func newOTLPHTTPExporter(endpoint string, settings map[string]string) (*otlpHTTPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid otlp http endpoint %s, should be like http://127.0.0.1:4318", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = otlpHTTPLogsPath
+	}
+
+	poster, err := newHTTPPosterWithSettings(u.String(), settings)
+	if err != nil {
+		return nil, err
+	}
+	return &otlpHTTPExporter{poster: poster}, nil
+}
+
+func (e *otlpHTTPExporter) export(request *collogspb.ExportLogsServiceRequest) error {
+	body, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+	_, err = e.poster.post(body, "application/x-protobuf")
+	return err
+}
+
+// otlpGRPCExporter calls the LogsService/Export, retry with backoff if the code is retryable
+type otlpGRPCExporter struct {
+	conn    *grpc.ClientConn
+	client  collogspb.LogsServiceClient
+	headers metadata.MD
+	timeout time.Duration
+
+	maxRetries      int
+	retryBackoff    time.Duration
+	maxRetryBackoff time.Duration
+}
+
+func newOTLPGRPCExporter(endpoint string, settings map[string]string) (*otlpGRPCExporter, error) {
+	e := &otlpGRPCExporter{headers: metadata.MD{}}
+
+	var err error
+	if e.timeout, err = getDurationSetting(settings, "timeout", defaultHTTPTimeout); err != nil {
+		return nil, err
+	}
+	if e.maxRetries, err = getIntSetting(settings, "max_retries", defaultHTTPMaxRetries); err != nil {
+		return nil, err
+	}
+	if e.retryBackoff, err = getDurationSetting(settings, "retry_backoff", defaultHTTPRetryBackoff); err != nil {
+		return nil, err
+	}
+	if e.maxRetryBackoff, err = getDurationSetting(settings, "max_retry_backoff", defaultHTTPMaxRetryBackoff); err != nil {
+		return nil, err
+	}
+
+	for k, v := range settings {
+		if strings.HasPrefix(k, httpHeaderSettingPrefix) {
+			e.headers.Set(strings.TrimPrefix(k, httpHeaderSettingPrefix), v)
+		}
+	}
+
+	options := []grpc.DialOption{}
+	if getBoolSetting(settings, "insecure", false) {
+		options = append(options, grpc.WithInsecure())
+	} else {
+		tlsConfig, err := getTLSConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
+	if getBoolSetting(settings, "gzip", false) {
+		options = append(options, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
+	// the dial is non-blocking, will connect in background
+	e.conn, err = grpc.Dial(endpoint, options...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to OTLP: %s", err)
+	}
+	e.client = collogspb.NewLogsServiceClient(e.conn)
+	return e, nil
+}
+
+func (e *otlpGRPCExporter) export(request *collogspb.ExportLogsServiceRequest) error {
+	var err error
+	backoff := e.retryBackoff
+	for i := 0; i <= e.maxRetries; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > e.maxRetryBackoff {
+				backoff = e.maxRetryBackoff
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), e.headers), e.timeout)
+		_, err = e.client.Export(ctx, request)
+		cancel()
+
+		if err == nil {
+			return nil
+		}
+		switch status.Code(err) {
+		case codes.Unavailable, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Aborted:
+		default:
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,297 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestNewOTLPHook(t *testing.T) {
+	f := OTLPLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing endpoint
+		{map[string]string{}, true},
+		// normal
+		{map[string]string{"endpoint": "http://127.0.0.1:4318"}, false},
+		{map[string]string{"endpoint": "127.0.0.1:4317", "protocol": "grpc", "insecure": "true", "gzip": "true"}, false},
+		{map[string]string{"endpoint": "127.0.0.1:4317", "protocol": "grpc", "header_x-token": "abc"}, false},
+		// wrong protocol
+		{map[string]string{"endpoint": "http://127.0.0.1:4318", "protocol": "http/json"}, true},
+		// wrong endpoint
+		{map[string]string{"endpoint": "127.0.0.1:4318"}, true},
+		// wrong settings
+		{map[string]string{"endpoint": "http://127.0.0.1:4318", "timeout": "a"}, true},
+		{map[string]string{"endpoint": "http://127.0.0.1:4318", "batch_max_count": "a"}, true},
+		{map[string]string{"endpoint": "127.0.0.1:4317", "protocol": "grpc", "timeout": "a"}, true},
+		{map[string]string{"endpoint": "127.0.0.1:4317", "protocol": "grpc", "tls_ca_file": "/not/exists"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestOTLPLogHookLevels(t *testing.T) {
+	h := OTLPLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+func TestOTLPSeverityNumber(t *testing.T) {
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_TRACE, otlpSeverityNumber(logrus.TraceLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, otlpSeverityNumber(logrus.DebugLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_INFO, otlpSeverityNumber(logrus.InfoLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, otlpSeverityNumber(logrus.WarnLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, otlpSeverityNumber(logrus.ErrorLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, otlpSeverityNumber(logrus.FatalLevel))
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4, otlpSeverityNumber(logrus.PanicLevel))
+}
+
+func TestNewOTLPAnyValue(t *testing.T) {
+	assert.Equal(t, "a", newOTLPAnyValue("a").GetStringValue())
+	assert.Equal(t, true, newOTLPAnyValue(true).GetBoolValue())
+	assert.Equal(t, int64(1), newOTLPAnyValue(1).GetIntValue())
+	assert.Equal(t, int64(2), newOTLPAnyValue(uint8(2)).GetIntValue())
+	assert.Equal(t, 1.5, newOTLPAnyValue(1.5).GetDoubleValue())
+	assert.Equal(t, []byte("a"), newOTLPAnyValue([]byte("a")).GetBytesValue())
+	assert.Equal(t, "wild walrus", newOTLPAnyValue(errors.New("wild walrus")).GetStringValue())
+	assert.Equal(t, "info", newOTLPAnyValue(logrus.InfoLevel).GetStringValue())
+	assert.Equal(t, "2020-04-01T00:00:00Z", newOTLPAnyValue(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)).GetStringValue())
+	assert.Equal(t, "{1}", newOTLPAnyValue(struct{ A int }{1}).GetStringValue())
+	assert.Nil(t, newOTLPAnyValue(nil).GetValue())
+
+	array := newOTLPAnyValue([]int{1, 2}).GetArrayValue()
+	assert.Len(t, array.Values, 2)
+	assert.Equal(t, int64(2), array.Values[1].GetIntValue())
+
+	kvlist := newOTLPAnyValue(map[string]interface{}{"b": 1, "a": "x"}).GetKvlistValue()
+	assert.Len(t, kvlist.Values, 2)
+	assert.Equal(t, "a", kvlist.Values[0].Key)
+	assert.Equal(t, "x", kvlist.Values[0].Value.GetStringValue())
+}
+
+func newTestOTLPEntry() *logrus.Entry {
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	return &logrus.Entry{
+		Message: "hello",
+		Level:   logrus.WarnLevel,
+		Time:    time.Unix(1585729801, 0),
+		Data:    logrus.Fields{"a": 1, "b": "x"},
+		Context: ctx,
+	}
+}
+
+func TestNewOTLPLogRecord(t *testing.T) {
+	record := newOTLPLogRecord(newTestOTLPEntry())
+
+	assert.Equal(t, uint64(1585729801000000000), record.TimeUnixNano)
+	assert.Equal(t, logspb.SeverityNumber_SEVERITY_NUMBER_WARN, record.SeverityNumber)
+	assert.Equal(t, "warning", record.SeverityText)
+	assert.Equal(t, "hello", record.Body.GetStringValue())
+	assert.Len(t, record.Attributes, 2)
+	assert.Equal(t, "a", record.Attributes[0].Key)
+	assert.Equal(t, int64(1), record.Attributes[0].Value.GetIntValue())
+	assert.Equal(t, []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}, record.TraceId)
+	assert.Equal(t, []byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}, record.SpanId)
+	assert.Equal(t, uint32(1), record.Flags)
+
+	// without context
+	entry := newTestOTLPEntry()
+	entry.Context = nil
+	record = newOTLPLogRecord(entry)
+	assert.Nil(t, record.TraceId)
+	assert.Nil(t, record.SpanId)
+}
+
+func assertOTLPRequest(t *testing.T, req *collogspb.ExportLogsServiceRequest, count int) {
+	assert.Len(t, req.ResourceLogs, 1)
+	resourceLogs := req.ResourceLogs[0]
+
+	assert.Equal(t, "service.name", resourceLogs.Resource.Attributes[0].Key)
+	assert.Equal(t, "myapp", resourceLogs.Resource.Attributes[0].Value.GetStringValue())
+
+	assert.Len(t, resourceLogs.ScopeLogs, 1)
+	scopeLogs := resourceLogs.ScopeLogs[0]
+	assert.Equal(t, otlpInstrumentationScope, scopeLogs.Scope.Name)
+	assert.Len(t, scopeLogs.LogRecords, count)
+	assert.Equal(t, "hello", scopeLogs.LogRecords[0].Body.GetStringValue())
+	assert.Len(t, scopeLogs.LogRecords[0].TraceId, 16)
+}
+
+func TestOTLPLogHookFireHTTP(t *testing.T) {
+	var mu sync.Mutex
+	var requests []*collogspb.ExportLogsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, otlpHTTPLogsPath, r.URL.Path)
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		body, _ := ioutil.ReadAll(r.Body)
+		req := &collogspb.ExportLogsServiceRequest{}
+		assert.NoError(t, proto.Unmarshal(body, req))
+
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	h, err := OTLPLogHookBuilder{}.New("otlp", map[string]string{
+		"endpoint":        server.URL,
+		"service_name":    "myapp",
+		"batch_max_count": "2",
+		"flush_interval":  "1h",
+	})
+	assert.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, h.Fire(newTestOTLPEntry()))
+	}
+	h.(*OTLPLogHook).Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, requests, 2)
+	assertOTLPRequest(t, requests[0], 2)
+	assertOTLPRequest(t, requests[1], 1)
+}
+
+type testOTLPLogsServer struct {
+	collogspb.UnimplementedLogsServiceServer
+
+	mu       sync.Mutex
+	failures int
+	requests []*collogspb.ExportLogsServiceRequest
+	tokens   []string
+}
+
+func (s *testOTLPLogsServer) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+		return nil, status.Error(codes.Unavailable, "unavailable")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	s.tokens = append(s.tokens, md.Get("x-token")...)
+	s.requests = append(s.requests, req)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+func TestOTLPLogHookFireGRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	// fail once, will retry
+	logsServer := &testOTLPLogsServer{failures: 1}
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, logsServer)
+	go server.Serve(ln) // nolint:errcheck
+	defer server.Stop()
+
+	h, err := OTLPLogHookBuilder{}.New("otlp", map[string]string{
+		"endpoint":       ln.Addr().String(),
+		"protocol":       "grpc",
+		"insecure":       "true",
+		"service_name":   "myapp",
+		"header_x-token": "abc",
+		"retry_backoff":  "1ms",
+		"async_enable":   "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(newTestOTLPEntry()))
+
+	logsServer.mu.Lock()
+	defer logsServer.mu.Unlock()
+	assert.Len(t, logsServer.requests, 1)
+	assertOTLPRequest(t, logsServer.requests[0], 1)
+	assert.Equal(t, []string{"abc"}, logsServer.tokens)
+}
+
+func TestOTLPGRPCExporterNotRetryable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	server := grpc.NewServer()
+	// without the LogsService, will be Unimplemented
+	go server.Serve(ln) // nolint:errcheck
+	defer server.Stop()
+
+	e, err := newOTLPGRPCExporter(ln.Addr().String(), map[string]string{"insecure": "true"})
+	assert.NoError(t, err)
+
+	err = e.export(&collogspb.ExportLogsServiceRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+// the request is built from the LogRecords, and the size of the record is counted into the batch bytes
+func TestOTLPLogHookSend(t *testing.T) {
+	var requests []*collogspb.ExportLogsServiceRequest
+	h := newOTLPHook(otlpExporterFunc(func(request *collogspb.ExportLogsServiceRequest) error {
+		requests = append(requests, request)
+		return nil
+	}), newOTLPResource("myapp"), batchConfig{maxCount: 1, maxBytes: 1, flushInterval: time.Hour}, false, 0, false)
+
+	assert.NoError(t, h.Fire(newTestOTLPEntry()))
+	if !assert.Len(t, requests, 1) {
+		return
+	}
+	assertOTLPRequest(t, requests[0], 1)
+
+	expected := &collogspb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: newOTLPResource("myapp"),
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: otlpInstrumentationScope},
+				LogRecords: []*logspb.LogRecord{newOTLPLogRecord(newTestOTLPEntry())},
+			}},
+		}},
+	}
+	assert.True(t, proto.Equal(expected, requests[0]))
+
+	_, size, err := h.convert(newTestOTLPEntry())
+	assert.NoError(t, err)
+	assert.Equal(t, proto.Size(newOTLPLogRecord(newTestOTLPEntry())), size)
+}
+
+type otlpExporterFunc func(request *collogspb.ExportLogsServiceRequest) error
+
+func (f otlpExporterFunc) export(request *collogspb.ExportLogsServiceRequest) error {
+	return f(request)
+}
@@ -0,0 +1,300 @@
+package hook
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	RedactModeMask = "mask"
+	RedactModeHash = "hash"
+
+	defaultRedactMask = "[REDACTED]"
+	// the settings with this prefix will be the custom patterns, e.g. `pattern_phone: \d{11}`
+	redactPatternSettingPrefix = "pattern_"
+	// the named group of the pattern, only the group is redacted if present, e.g. the token after `Bearer `
+	redactSecretGroup = "secret"
+	// the max depth of the nested maps to redact
+	maxRedactDepth = 8
+)
+
+// DefaultRedactFields is the denylist of the field names when the setting `fields` is absent
+var DefaultRedactFields = []string{
+	"password", "passwd", "pwd", "secret", "*token*", "authorization", "cookie", "api_key", "apikey", "*secret_key*",
+}
+
+// DefaultRedactPatterns is the builtin patterns enabled when the setting `patterns` is absent
+var DefaultRedactPatterns = []string{"credit_card", "bearer"}
+
+type redactPattern struct {
+	re *regexp.Regexp
+	// the index of the named group `secret`, 0 is the whole match
+	group int
+	// valid checks the matched text to avoid the false positives, e.g. the luhn check of credit card
+	valid func(string) bool
+}
+
+func newRedactPattern(re *regexp.Regexp, valid func(string) bool) redactPattern {
+	p := redactPattern{re: re, valid: valid}
+	for i, name := range re.SubexpNames() {
+		if name == redactSecretGroup {
+			p.group = i
+		}
+	}
+	return p
+}
+
+var builtinRedactPatterns = map[string]redactPattern{
+	"credit_card": newRedactPattern(regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), luhnValid),
+	"bearer":      newRedactPattern(regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[a-z0-9\-._~+/]+=*)`), nil),
+	"email":       newRedactPattern(regexp.MustCompile(`[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}`), nil),
+}
+
+type RedactLogHookBuilder struct {
+}
+
+// redact: redact the sensitive data of the entry in place, should be the first hook,
+// so the following hooks and the formatter will get the redacted entry
+func (b RedactLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	redactor, err := NewRedactor(settings)
+	if err != nil {
+		return nil, err
+	}
+	return &RedactLogHook{redactor: redactor}, nil
+}
+
+// Redactor masks or hashes the denylisted fields, and the text matched by the patterns in message and string values
+type Redactor struct {
+	fields   []string
+	patterns []redactPattern
+	mode     string
+	mask     string
+	salt     string
+}
+
+// NewRedactor creates the redactor with settings:
+// `fields` the denylisted field names, comma separated, case-insensitive glob, e.g. `password,*token*`
+// `patterns` the builtin patterns, comma separated, credit_card, bearer and email
+// `pattern_{name}` the custom regex pattern, only the named group `secret` is redacted if present
+// `mode` mask or hash, `mask` the replacement of mask mode, `hash_salt` the salt of hash mode
+func NewRedactor(settings map[string]string) (*Redactor, error) {
+	r := &Redactor{
+		fields: append([]string(nil), DefaultRedactFields...),
+		mode:   RedactModeMask,
+		mask:   defaultRedactMask,
+		salt:   settings["hash_salt"],
+	}
+
+	if fields, ok := settings["fields"]; ok {
+		r.fields = splitSetting(fields)
+	}
+	for i, field := range r.fields {
+		field = strings.ToLower(field)
+		if _, err := path.Match(field, ""); err != nil {
+			return nil, fmt.Errorf("invalid redact field pattern %s: %s", field, err)
+		}
+		r.fields[i] = field
+	}
+
+	patterns := DefaultRedactPatterns
+	if value, ok := settings["patterns"]; ok {
+		patterns = splitSetting(value)
+	}
+	for _, name := range patterns {
+		p, ok := builtinRedactPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported redact pattern %s, should be credit_card, bearer or email", name)
+		}
+		r.patterns = append(r.patterns, p)
+	}
+
+	// the custom patterns in order of name, to be deterministic
+	var names []string
+	for k := range settings {
+		if strings.HasPrefix(k, redactPatternSettingPrefix) {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		re, err := regexp.Compile(settings[k])
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %s: %s", k, err)
+		}
+		r.patterns = append(r.patterns, newRedactPattern(re, nil))
+	}
+
+	if mode, ok := settings["mode"]; ok {
+		if mode != RedactModeMask && mode != RedactModeHash {
+			return nil, fmt.Errorf("unsupported redact mode %s, should be mask or hash", mode)
+		}
+		r.mode = mode
+	}
+	if mask, ok := settings["mask"]; ok {
+		r.mask = mask
+	}
+	return r, nil
+}
+
+// splitSetting splits the comma separated setting, the empty items are ignored
+func splitSetting(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// Redact redacts the message and the fields of the entry, entry.Data is replaced by a redacted copy
+// instead of being modified, the map may be shared with the *logrus.Entry held by the caller
+func (r *Redactor) Redact(entry *logrus.Entry) {
+	entry.Message = r.redactString(entry.Message)
+	if len(entry.Data) == 0 {
+		return
+	}
+
+	data := make(logrus.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		data[k] = r.redactField(k, v, 0)
+	}
+	entry.Data = data
+}
+
+func (r *Redactor) redactField(key string, value interface{}, depth int) interface{} {
+	if r.isDenied(key) {
+		return r.replace(fmt.Sprint(value))
+	}
+	return r.redactValue(value, depth)
+}
+
+func (r *Redactor) redactValue(value interface{}, depth int) interface{} {
+	switch v := value.(type) {
+	case string:
+		return r.redactString(v)
+	case error:
+		// the error is kept if nothing matched, for the formatters with rich errors
+		msg := v.Error()
+		if redacted := r.redactString(msg); redacted != msg {
+			return redacted
+		}
+		return v
+	case map[string]interface{}:
+		if depth >= maxRedactDepth {
+			return v
+		}
+		m := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			m[k] = r.redactField(k, x, depth+1)
+		}
+		return m
+	case logrus.Fields:
+		if depth >= maxRedactDepth {
+			return v
+		}
+		m := make(logrus.Fields, len(v))
+		for k, x := range v {
+			m[k] = r.redactField(k, x, depth+1)
+		}
+		return m
+	case map[string]string:
+		m := make(map[string]string, len(v))
+		for k, x := range v {
+			if r.isDenied(k) {
+				m[k] = r.replace(x)
+			} else {
+				m[k] = r.redactString(x)
+			}
+		}
+		return m
+	}
+	return value
+}
+
+func (r *Redactor) isDenied(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range r.fields {
+		if ok, _ := path.Match(field, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactString replaces the text matched by the patterns, or the named group `secret` of it
+func (r *Redactor) redactString(s string) string {
+	for _, p := range r.patterns {
+		var b strings.Builder
+		last, replaced := 0, false
+		for _, loc := range p.re.FindAllStringSubmatchIndex(s, -1) {
+			start, end := loc[2*p.group], loc[2*p.group+1]
+			if start < 0 {
+				continue
+			}
+			if p.valid != nil && !p.valid(s[start:end]) {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.replace(s[start:end]))
+			last, replaced = end, true
+		}
+		if replaced {
+			b.WriteString(s[last:])
+			s = b.String()
+		}
+	}
+	return s
+}
+
+func (r *Redactor) replace(s string) string {
+	if r.mode == RedactModeHash {
+		sum := sha256.Sum256([]byte(r.salt + s))
+		return "sha256:" + hex.EncodeToString(sum[:])[:16]
+	}
+	return r.mask
+}
+
+// luhnValid checks the digits(spaces and dashes are ignored) by the luhn algorithm
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// RedactLogHook redacts the entries before the following hooks and the formatter
+type RedactLogHook struct {
+	redactor *Redactor
+}
+
+// Fire is called when a log event is fired.
+func (h *RedactLogHook) Fire(entry *logrus.Entry) error {
+	h.redactor.Redact(entry)
+	return nil
+}
+
+// Levels returns the available logging levels.
+func (h *RedactLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
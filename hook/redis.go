@@ -1,6 +1,7 @@
 package hook
 
 import (
+	"bytes"
 	"fmt"
 	"strconv"
 	"time"
@@ -9,6 +10,8 @@ import (
 	jsoniter "github.com/json-iterator/go"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+
+	"github.com/wklken/logging-go/formatter"
 )
 
 // TODO: 1. settings here
@@ -53,6 +56,7 @@ func (b RedisLogHookBuilder) New(name string, settings map[string]string) (logru
 	if hostname, ok := settings["hostname"]; ok {
 		hookConfig.Hostname = hostname
 	}
+	hookConfig.DefaultHostname = settings["default_hostname"] == "true"
 	if logformat, ok := settings["logformat"]; ok {
 		hookConfig.LogFormat = logformat
 	}
@@ -76,6 +80,12 @@ func (b RedisLogHookBuilder) New(name string, settings map[string]string) (logru
 	// if AsyncEnable && hookConfig.asyncBufferSize == 0 {
 	// 	hookConfig.asyncBufferSize = DefaultAsyncBufferSize
 	// }
+	limits, err := formatter.ParseLimits(settings)
+	if err != nil {
+		return nil, err
+	}
+	hookConfig.Limits = limits
+
 	asyncEnable, asyncBufferSize, asyncBlock := getAsyncSettings(settings)
 	hookConfig.asyncEnable = asyncEnable
 	hookConfig.asyncBufferSize = asyncBufferSize
@@ -100,8 +110,12 @@ type RedisHookConfig struct {
 
 	App      string
 	Hostname string
+	// DefaultHostname uses the hostname of the machine if Hostname is empty
+	DefaultHostname bool
 
 	LogFormat string
+	// Limits limits the size of the message, applied to all the logformats
+	Limits formatter.Limits
 
 	asyncEnable     bool
 	asyncBufferSize int
@@ -112,10 +126,9 @@ type RedisHookConfig struct {
 type RedisLogHook struct {
 	redisClient *redis.Client
 	redisKey    string
-	logFormat   string
-
-	app      string
-	hostname string
+	// formatter is nil for the default json message
+	formatter logrus.Formatter
+	limits    formatter.Limits
 
 	fireChannel     chan *logrus.Entry
 	asyncEnable     bool
@@ -134,10 +147,8 @@ func newRedisHook(config RedisHookConfig) (*RedisLogHook, error) {
 	hook := &RedisLogHook{
 		redisClient: redisClient,
 		redisKey:    config.Key,
-
-		app:       config.App,
-		hostname:  config.Hostname,
-		logFormat: config.LogFormat,
+		limits:      config.Limits,
+		formatter:   newRedisFormatter(config),
 	}
 
 	if config.asyncEnable {
@@ -151,6 +162,22 @@ func newRedisHook(config RedisHookConfig) (*RedisLogHook, error) {
 	return hook, nil
 }
 
+// newRedisFormatter returns the formatter of the logformat, nil for the default json message
+func newRedisFormatter(config RedisHookConfig) logrus.Formatter {
+	switch config.LogFormat {
+	case "logstashv0":
+		return &formatter.LogstashFormatter{Version: formatter.LogstashV0, Application: config.App,
+			Hostname: config.Hostname, DefaultHostname: config.DefaultHostname}
+	case "logstashv1":
+		return &formatter.LogstashFormatter{Version: formatter.LogstashV1, Application: config.App,
+			Hostname: config.Hostname, DefaultHostname: config.DefaultHostname}
+	case "ecs":
+		return &formatter.ECSFormatter{ServiceName: config.App,
+			Hostname: config.Hostname, DefaultHostname: config.DefaultHostname}
+	}
+	return nil
+}
+
 func (r *RedisLogHook) makeAsync() {
 	r.fireChannel = make(chan *logrus.Entry, r.asyncBufferSize)
 	fmt.Printf("redis hook will use a async buffer with size %d\n", r.asyncBufferSize)
@@ -166,15 +193,8 @@ func (r *RedisLogHook) makeAsync() {
 // Fire is called when a log event is fired.
 func (r *RedisLogHook) Fire(entry *logrus.Entry) error {
 	if r.fireChannel != nil { // Async mode.
-		select {
-		case r.fireChannel <- entry:
-		default:
-			if r.asyncBlock {
-				r.fireChannel <- entry // Blocks the goroutine because buffer is full.
-				return nil
-			}
-			// Drop message by default.
-		}
+		// put a copy, the entry.Buffer is put back into the pool by logrus after written
+		putEntry(r.fireChannel, entry, r.asyncBlock)
 		return nil
 	}
 
@@ -182,20 +202,14 @@ func (r *RedisLogHook) Fire(entry *logrus.Entry) error {
 }
 
 func (r *RedisLogHook) send(entry *logrus.Entry) error {
-	var msg interface{}
+	var js []byte
+	var err error
 
-	switch r.logFormat {
-	case "logstashv0":
-		msg = createV0Message(entry, r.app, r.hostname)
-	case "logstashv1":
-		msg = createV1Message(entry, r.app, r.hostname)
-	case "json":
-	default:
-		msg = createMessage(entry)
+	if r.limits.Enabled() {
+		js, err = r.limits.Format(entry, r.format)
+	} else {
+		js, err = r.format(entry)
 	}
-
-	// Marshal into json message
-	js, err := jsoniter.Marshal(msg)
 	if err != nil {
 		return fmt.Errorf("error creating message for REDIS: %s", err)
 	}
@@ -211,6 +225,15 @@ func (r *RedisLogHook) send(entry *logrus.Entry) error {
 	return nil
 }
 
+func (r *RedisLogHook) format(entry *logrus.Entry) ([]byte, error) {
+	if r.formatter != nil {
+		js, err := r.formatter.Format(entry)
+		return bytes.TrimRight(js, "\n"), err
+	}
+	// Marshal into json message
+	return jsoniter.Marshal(createMessage(entry))
+}
+
 // Levels returns the available logging levels.
 func (r *RedisLogHook) Levels() []logrus.Level {
 	return []logrus.Level{
@@ -237,38 +260,6 @@ func createMessage(entry *logrus.Entry) map[string]interface{} {
 	return m
 }
 
-func createV0Message(entry *logrus.Entry, appName, hostname string) map[string]interface{} {
-	m := make(map[string]interface{})
-	m["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
-	m["@source_host"] = hostname
-	m["@message"] = entry.Message
-
-	fields := make(map[string]interface{})
-	fields["level"] = entry.Level.String()
-	fields["application"] = appName
-
-	for k, v := range entry.Data {
-		fields[k] = v
-	}
-	m["@fields"] = fields
-
-	return m
-}
-
-func createV1Message(entry *logrus.Entry, appName, hostname string) map[string]interface{} {
-	m := make(map[string]interface{})
-	m["@timestamp"] = entry.Time.UTC().Format(time.RFC3339Nano)
-	m["host"] = hostname
-	m["message"] = entry.Message
-	m["level"] = entry.Level.String()
-	m["application"] = appName
-	for k, v := range entry.Data {
-		m[k] = v
-	}
-
-	return m
-}
-
 func newRedisClient(server, password string, port int, db int, poolSize int) (*redis.Client, error) {
 	addr := fmt.Sprintf("%s:%d", server, port)
 
@@ -1,11 +1,16 @@
 package hook
 
 import (
+	"encoding/json"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/wklken/logging-go/formatter"
 )
 
 func TestNewRedisHook(t *testing.T) {
@@ -26,6 +31,8 @@ func TestNewRedisHook(t *testing.T) {
 		{map[string]string{"host": "127.1.1.1", "port": "a", "db": "0", "key": "test"}, true},
 		// normal wrong db
 		{map[string]string{"host": "127.1.1.1", "port": "6379", "db": "a", "key": "test"}, true},
+		// wrong limits
+		{map[string]string{"host": "127.1.1.1", "port": "6379", "db": "0", "key": "test", "max_fields": "a"}, true},
 	}
 	for _, d := range data {
 		_, err := f.New(name, d.settings)
@@ -73,14 +80,69 @@ func TestCreateMessage(t *testing.T) {
 	m1 := createMessage(entry)
 	assert.Equal(t, "hello", m1["message"].(string))
 
-	m2 := createV0Message(entry, "app1", "localhost")
+	m2 := formatRedisMessage(t, RedisHookConfig{LogFormat: "logstashv0", App: "app1", Hostname: "localhost"}, entry)
 	assert.Equal(t, "hello", m2["@message"])
 	assert.Equal(t, "localhost", m2["@source_host"])
 	assert.Len(t, m2["@fields"], 3)
 
-	m3 := createV1Message(entry, "app1", "localhost")
+	m3 := formatRedisMessage(t, RedisHookConfig{LogFormat: "logstashv1", App: "app1", Hostname: "localhost"}, entry)
 	assert.Equal(t, "hello", m3["message"])
 	assert.Equal(t, "localhost", m3["host"])
 	assert.Equal(t, "app1", m3["application"])
-	assert.Equal(t, 1, m3["a"])
+	assert.Equal(t, float64(1), m3["a"])
+
+	// the hostname is empty if not set, same as before
+	m2 = formatRedisMessage(t, RedisHookConfig{LogFormat: "logstashv0"}, entry)
+	assert.Equal(t, "", m2["@source_host"])
+	m3 = formatRedisMessage(t, RedisHookConfig{LogFormat: "logstashv1"}, entry)
+	assert.Equal(t, "", m3["host"])
+
+	// the hostname of machine
+	hostname, _ := os.Hostname()
+	m3 = formatRedisMessage(t, RedisHookConfig{LogFormat: "logstashv1", DefaultHostname: true}, entry)
+	assert.Equal(t, hostname, m3["host"])
+
+	assert.Nil(t, newRedisFormatter(RedisHookConfig{LogFormat: "json"}))
+}
+
+func formatRedisMessage(t *testing.T, config RedisHookConfig, entry *logrus.Entry) map[string]interface{} {
+	b, err := newRedisFormatter(config).Format(entry)
+	assert.NoError(t, err)
+
+	m := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(b, &m))
+	return m
+}
+
+func TestRedisLogHookFormatLimits(t *testing.T) {
+	entry := &logrus.Entry{
+		Message: "hello world",
+		Level:   logrus.InfoLevel,
+		Time:    time.Now(),
+		Data:    logrus.Fields{"body": strings.Repeat("a", 100), "a": 1},
+	}
+
+	h := &RedisLogHook{limits: formatter.Limits{MaxMessageLength: 5, MaxFieldLength: 10}}
+	js, err := h.limits.Format(entry, h.format)
+	assert.NoError(t, err)
+
+	m := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(js, &m))
+	assert.Equal(t, "hello"+formatter.TruncatedMarker, m["message"])
+	assert.Equal(t, "aaaaaaaaaa"+formatter.TruncatedMarker, m["body"])
+	assert.Equal(t, float64(1), m["a"])
+	assert.Equal(t, true, m[formatter.FieldKeyTruncated])
+
+	// the logformat formatter
+	h.formatter = &formatter.ECSFormatter{ServiceName: "app"}
+	js, err = h.limits.Format(entry, h.format)
+	assert.NoError(t, err)
+	m = map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(js, &m))
+	assert.Equal(t, "hello"+formatter.TruncatedMarker, m["message"])
+	assert.Equal(t, true, m[formatter.FieldKeyTruncated])
+
+	// the entry is not modified
+	assert.Equal(t, "hello world", entry.Message)
+	assert.Len(t, entry.Data, 2)
 }
@@ -0,0 +1,271 @@
+package hook
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	samplingSummaryMessage = "log entries suppressed by sampling"
+	// the fields of the summary entry, the total and the counts by level
+	samplingSummaryKey       = "sampling_suppressed"
+	samplingSummaryLevelsKey = "sampling_suppressed_levels"
+	// the mark of the suppressed entries, checked by the wrapped hooks and formatter
+	samplingSuppressedKey = "_sampling_suppressed"
+
+	defaultSamplingTick            = time.Second
+	defaultSamplingSummaryInterval = 10 * time.Second
+	// the counters of messages are reset if too many distinct messages in one tick
+	maxSamplingMessages = 10000
+)
+
+type SamplingLogHookBuilder struct {
+}
+
+// sampling: sample the entries in front of all the other hooks and the formatter, the error and above are never sampled
+// - the first N entries of the same level and message per tick, then 1 in M
+// - token bucket per level
+// the suppressed entries are counted and emitted periodically as a summary entry at warning level
+func (b SamplingLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	h := &SamplingLogHook{
+		buckets:    map[logrus.Level]*tokenBucket{},
+		counts:     map[string]int{},
+		suppressed: map[logrus.Level]int64{},
+		now:        time.Now,
+		done:       make(chan struct{}),
+	}
+
+	var err error
+	if h.first, err = getIntSetting(settings, "first", 0); err != nil {
+		return nil, err
+	}
+	if h.thereafter, err = getIntSetting(settings, "thereafter", 0); err != nil {
+		return nil, err
+	}
+	if h.tick, err = getDurationSetting(settings, "tick", defaultSamplingTick); err != nil {
+		return nil, err
+	}
+	if h.first > 0 && h.tick <= 0 {
+		return nil, fmt.Errorf("tick should be positive")
+	}
+
+	for _, level := range logrus.AllLevels {
+		value, ok := settings["rate_"+level.String()]
+		if !ok {
+			continue
+		}
+		if level <= logrus.ErrorLevel {
+			return nil, fmt.Errorf("rate_%s is not supported, %s and above are never sampled", level, logrus.ErrorLevel)
+		}
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("rate_%s should be positive number", level)
+		}
+		burst := rate
+		if burst < 1 {
+			burst = 1
+		}
+		if value, ok := settings["burst_"+level.String()]; ok {
+			if burst, err = strconv.ParseFloat(value, 64); err != nil || burst < 1 {
+				return nil, fmt.Errorf("burst_%s should be number not less than 1", level)
+			}
+		}
+		h.buckets[level] = &tokenBucket{rate: rate, burst: burst, tokens: burst}
+	}
+
+	interval, err := getDurationSetting(settings, "summary_interval", defaultSamplingSummaryInterval)
+	if err != nil {
+		return nil, err
+	}
+	if interval > 0 {
+		go h.run(interval)
+	}
+	return h, nil
+}
+
+// SamplingLogHook decides whether the entry is sampled, should be the first hook,
+// the other hooks and the formatter should be wrapped by Wrap and WrapFormatter
+type SamplingLogHook struct {
+	first      int
+	thereafter int
+	tick       time.Duration
+	buckets    map[logrus.Level]*tokenBucket
+
+	mu        sync.Mutex
+	tickStart time.Time
+	counts    map[string]int
+	// the suppressed counts by level since the last summary
+	suppressed map[logrus.Level]int64
+	logger     *logrus.Logger
+	now        func() time.Time
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Fire is called when a log event is fired, the suppressed entry is marked,
+// so the wrapped hooks and formatter skip it
+func (h *SamplingLogHook) Fire(entry *logrus.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if entry.Logger != nil {
+		h.logger = entry.Logger
+	}
+	if h.sample(entry) {
+		return nil
+	}
+	h.suppressed[entry.Level]++
+
+	// the data may be shared with the entry held by the caller, so copy it
+	data := make(logrus.Fields, len(entry.Data)+1)
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	data[samplingSuppressedKey] = true
+	entry.Data = data
+	return nil
+}
+
+// Close stops the periodic summary
+func (h *SamplingLogHook) Close() {
+	h.closeOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+func (h *SamplingLogHook) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.summary()
+		case <-h.done:
+			return
+		}
+	}
+}
+
+// Levels returns the available logging levels.
+func (h *SamplingLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *SamplingLogHook) sample(entry *logrus.Entry) bool {
+	if entry.Level <= logrus.ErrorLevel {
+		return true
+	}
+	if _, ok := entry.Data[samplingSummaryKey]; ok && entry.Message == samplingSummaryMessage {
+		return true
+	}
+
+	now := h.now()
+	if h.first > 0 {
+		if now.Sub(h.tickStart) >= h.tick || len(h.counts) >= maxSamplingMessages {
+			h.counts = map[string]int{}
+			h.tickStart = now
+		}
+		key := entry.Level.String() + "|" + entry.Message
+		n := h.counts[key] + 1
+		h.counts[key] = n
+		if n > h.first && (h.thereafter <= 0 || (n-h.first)%h.thereafter != 0) {
+			return false
+		}
+	}
+
+	if b, ok := h.buckets[entry.Level]; ok && !b.allow(now) {
+		return false
+	}
+	return true
+}
+
+// allowed returns false if the entry is marked as suppressed
+func (h *SamplingLogHook) allowed(entry *logrus.Entry) bool {
+	_, ok := entry.Data[samplingSuppressedKey]
+	return !ok
+}
+
+// summary logs the suppressed counts since the last summary
+func (h *SamplingLogHook) summary() {
+	h.mu.Lock()
+	logger := h.logger
+	var total int64
+	levels := make(map[string]int64, len(h.suppressed))
+	for level, n := range h.suppressed {
+		total += n
+		levels[level.String()] = n
+	}
+	h.suppressed = map[logrus.Level]int64{}
+	h.mu.Unlock()
+
+	if total == 0 || logger == nil {
+		return
+	}
+	logger.WithFields(logrus.Fields{
+		samplingSummaryKey:       total,
+		samplingSummaryLevelsKey: levels,
+	}).Warn(samplingSummaryMessage)
+}
+
+// Wrap returns the hook which only fires the sampled entries
+func (h *SamplingLogHook) Wrap(hook logrus.Hook) logrus.Hook {
+	return &sampledHook{Hook: hook, sampling: h}
+}
+
+// WrapFormatter returns the formatter which only formats the sampled entries, should be the formatter of logger
+func (h *SamplingLogHook) WrapFormatter(formatter logrus.Formatter) logrus.Formatter {
+	return &sampledFormatter{Formatter: formatter, sampling: h}
+}
+
+type sampledHook struct {
+	logrus.Hook
+	sampling *SamplingLogHook
+}
+
+// Fire is called when a log event is fired.
+func (h *sampledHook) Fire(entry *logrus.Entry) error {
+	if !h.sampling.allowed(entry) {
+		return nil
+	}
+	return h.Hook.Fire(entry)
+}
+
+type sampledFormatter struct {
+	logrus.Formatter
+	sampling *SamplingLogHook
+}
+
+// Format renders a single log entry, nothing for the suppressed entries
+func (f *sampledFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if !f.sampling.allowed(entry) {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) allow(now time.Time) bool {
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
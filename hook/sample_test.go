@@ -0,0 +1,226 @@
+package hook
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSamplingRecorder struct {
+	messages []string
+}
+
+func (r *testSamplingRecorder) Fire(entry *logrus.Entry) error {
+	r.messages = append(r.messages, entry.Message)
+	return nil
+}
+
+func (r *testSamplingRecorder) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func newTestSamplingHook(t *testing.T, settings map[string]string) (*SamplingLogHook, *time.Time) {
+	settings["summary_interval"] = "0"
+	h, err := SamplingLogHookBuilder{}.New("sampling", settings)
+	assert.NoError(t, err)
+
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	sampling := h.(*SamplingLogHook)
+	sampling.now = func() time.Time {
+		return now
+	}
+	return sampling, &now
+}
+
+func TestSamplingLogHookBuilder(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]string
+		wantErr  bool
+	}{
+		{name: "empty", settings: map[string]string{}},
+		{name: "all", settings: map[string]string{
+			"first": "10", "thereafter": "100", "tick": "1s", "rate_info": "100", "burst_info": "200",
+			"rate_debug": "0.5", "summary_interval": "1m",
+		}},
+		{name: "invalid first", settings: map[string]string{"first": "a"}, wantErr: true},
+		{name: "invalid thereafter", settings: map[string]string{"thereafter": "a"}, wantErr: true},
+		{name: "invalid tick", settings: map[string]string{"first": "1", "tick": "0s"}, wantErr: true},
+		{name: "invalid rate", settings: map[string]string{"rate_info": "0"}, wantErr: true},
+		{name: "invalid burst", settings: map[string]string{"rate_info": "1", "burst_info": "0.5"}, wantErr: true},
+		{name: "error is never sampled", settings: map[string]string{"rate_error": "1"}, wantErr: true},
+		{name: "invalid summary interval", settings: map[string]string{"summary_interval": "a"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := SamplingLogHookBuilder{}.New("sampling", tt.settings)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, logrus.AllLevels, h.Levels())
+		})
+	}
+}
+
+func TestSamplingLogHookFirstThereafter(t *testing.T) {
+	h, now := newTestSamplingHook(t, map[string]string{"first": "2", "thereafter": "3"})
+
+	sample := func(level logrus.Level, msg string) bool {
+		return h.sample(&logrus.Entry{Level: level, Message: msg})
+	}
+
+	var got []bool
+	for i := 0; i < 8; i++ {
+		got = append(got, sample(logrus.InfoLevel, "hello"))
+	}
+	// the first 2, then 1 in 3
+	assert.Equal(t, []bool{true, true, false, false, true, false, false, true}, got)
+
+	// by level and message
+	assert.True(t, sample(logrus.InfoLevel, "other"))
+	assert.True(t, sample(logrus.WarnLevel, "hello"))
+	// error and above are never sampled
+	for i := 0; i < 5; i++ {
+		assert.True(t, sample(logrus.ErrorLevel, "hello"))
+	}
+
+	// the next tick
+	*now = now.Add(time.Second)
+	assert.True(t, sample(logrus.InfoLevel, "hello"))
+	assert.True(t, sample(logrus.InfoLevel, "hello"))
+	assert.False(t, sample(logrus.InfoLevel, "hello"))
+
+	// drop all after the first
+	h, _ = newTestSamplingHook(t, map[string]string{"first": "1"})
+	assert.True(t, sample(logrus.InfoLevel, "hello"))
+	for i := 0; i < 5; i++ {
+		assert.False(t, sample(logrus.InfoLevel, "hello"))
+	}
+}
+
+func TestSamplingLogHookTokenBucket(t *testing.T) {
+	h, now := newTestSamplingHook(t, map[string]string{"rate_info": "2", "burst_info": "3"})
+
+	sample := func(level logrus.Level) bool {
+		return h.sample(&logrus.Entry{Level: level, Message: "hello"})
+	}
+
+	// the burst
+	assert.True(t, sample(logrus.InfoLevel))
+	assert.True(t, sample(logrus.InfoLevel))
+	assert.True(t, sample(logrus.InfoLevel))
+	assert.False(t, sample(logrus.InfoLevel))
+	// other levels are not limited
+	assert.True(t, sample(logrus.DebugLevel))
+
+	// 2 tokens per second
+	*now = now.Add(500 * time.Millisecond)
+	assert.True(t, sample(logrus.InfoLevel))
+	assert.False(t, sample(logrus.InfoLevel))
+	*now = now.Add(10 * time.Second)
+	assert.True(t, sample(logrus.InfoLevel))
+	assert.True(t, sample(logrus.InfoLevel))
+	assert.True(t, sample(logrus.InfoLevel))
+	assert.False(t, sample(logrus.InfoLevel))
+}
+
+func TestSamplingLogHookLogger(t *testing.T) {
+	h, _ := newTestSamplingHook(t, map[string]string{"first": "2"})
+
+	var buf bytes.Buffer
+	recorder := &testSamplingRecorder{}
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(h.WrapFormatter(&logrus.TextFormatter{DisableTimestamp: true}))
+	logger.AddHook(h)
+	logger.AddHook(h.Wrap(recorder))
+
+	for i := 0; i < 5; i++ {
+		logger.Info("hello")
+	}
+	logger.Debug("debug")
+	logger.Warn("world")
+	for i := 0; i < 3; i++ {
+		logger.Error("failed")
+	}
+
+	assert.Equal(t, []string{"hello", "hello", "world", "failed", "failed", "failed"}, recorder.messages)
+	assert.Equal(t, 6, strings.Count(buf.String(), "\n"))
+	// the mark is not written
+	assert.NotContains(t, buf.String(), samplingSuppressedKey)
+
+	// the summary
+	recorder.messages = nil
+	buf.Reset()
+	h.summary()
+	assert.Equal(t, []string{samplingSummaryMessage}, recorder.messages)
+	assert.Contains(t, buf.String(), "sampling_suppressed=3")
+	assert.Contains(t, buf.String(), "sampling_suppressed_levels=\"map[info:3]\"")
+
+	// nothing suppressed since the last summary
+	recorder.messages = nil
+	h.summary()
+	assert.Empty(t, recorder.messages)
+
+	// the wrappers can be stacked, e.g. ApplyAsStdLogger twice
+	buf.Reset()
+	logger.SetFormatter(h.WrapFormatter(logger.Formatter))
+	logger.Info("hello")
+	logger.Warn("world")
+	assert.Equal(t, 1, strings.Count(buf.String(), "\n"))
+	assert.Equal(t, []string{"world"}, recorder.messages)
+}
+
+type testSamplingSummaryHook struct {
+	summaries chan struct{}
+}
+
+func (r *testSamplingSummaryHook) Fire(entry *logrus.Entry) error {
+	if entry.Message == samplingSummaryMessage {
+		r.summaries <- struct{}{}
+	}
+	return nil
+}
+
+func (r *testSamplingSummaryHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func TestSamplingLogHookClose(t *testing.T) {
+	h, err := SamplingLogHookBuilder{}.New("sampling", map[string]string{"first": "1", "summary_interval": "10ms"})
+	assert.NoError(t, err)
+	sampling := h.(*SamplingLogHook)
+
+	recorder := &testSamplingSummaryHook{summaries: make(chan struct{}, 1)}
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	logger.AddHook(sampling)
+	logger.AddHook(sampling.Wrap(recorder))
+
+	logger.Info("hello")
+	logger.Info("hello")
+	select {
+	case <-recorder.summaries:
+	case <-time.After(time.Second):
+		t.Fatal("no summary")
+	}
+
+	sampling.Close()
+	// closed twice is ok
+	sampling.Close()
+	// nothing is summarized after closed
+	logger.Info("hello")
+	select {
+	case <-recorder.summaries:
+		t.Fatal("summary after closed")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,353 @@
+package hook
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/wklken/logging-go/internal/syslog"
+)
+
+const (
+	SyslogRFC5424 = "rfc5424"
+	SyslogRFC3164 = "rfc3164"
+
+	defaultSyslogPort    = "514"
+	defaultSyslogTLSPort = "6514"
+	// 32473 is the private enterprise number reserved for documentation, see rfc5612
+	defaultSyslogSDID = "fields@32473"
+
+	rfc5424TimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+	rfc3164TimeFormat = "Jan _2 15:04:05"
+)
+
+var syslogFacilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+type SyslogLogHookBuilder struct {
+}
+
+// syslog: rfc5424 https://tools.ietf.org/html/rfc5424 and rfc3164 https://tools.ietf.org/html/rfc3164
+func (b SyslogLogHookBuilder) New(name string, settings map[string]string) (logrus.Hook, error) {
+	network, ok := settings["network"]
+	if !ok {
+		network = "udp"
+	}
+
+	config := SyslogHookConfig{
+		Protocol: SyslogRFC5424,
+		Facility: syslogFacilities["user"],
+		Tag:      filepath.Base(os.Args[0]),
+		SDID:     defaultSyslogSDID,
+	}
+
+	var address string
+	switch network {
+	case "unix", "unixgram":
+		if err := validateRequiredHookSettings(name, settings, []string{"path"}); err != nil {
+			return nil, err
+		}
+		address = settings["path"]
+	case "udp", "tcp", "tls":
+		if err := validateRequiredHookSettings(name, settings, []string{"host"}); err != nil {
+			return nil, err
+		}
+		port, ok := settings["port"]
+		if !ok {
+			port = defaultSyslogPort
+			if network == "tls" {
+				port = defaultSyslogTLSPort
+			}
+		}
+		if _, err := strconv.Atoi(port); err != nil {
+			return nil, errors.New("port should be integer")
+		}
+		address = net.JoinHostPort(settings["host"], port)
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %s, should be one of udp/tcp/tls/unix/unixgram", network)
+	}
+
+	if protocol, ok := settings["protocol"]; ok {
+		if protocol != SyslogRFC5424 && protocol != SyslogRFC3164 {
+			return nil, fmt.Errorf("unsupported syslog protocol %s, should be rfc5424 or rfc3164", protocol)
+		}
+		config.Protocol = protocol
+	}
+
+	if facility, ok := settings["facility"]; ok {
+		f, err := parseSyslogFacility(facility)
+		if err != nil {
+			return nil, err
+		}
+		config.Facility = f
+	}
+
+	if tag, ok := settings["tag"]; ok {
+		config.Tag = tag
+	}
+	if hostname, ok := settings["hostname"]; ok {
+		config.Hostname = hostname
+	} else {
+		config.Hostname, _ = os.Hostname()
+	}
+	if msgID, ok := settings["msg_id"]; ok {
+		config.MsgID = msgID
+	}
+	if sdID, ok := settings["sd_id"]; ok {
+		config.SDID = sdID
+	}
+
+	// rfc5424 over stream use octet counting by default, rfc3164 use the traditional newline
+	config.Framing = FramingOctetCounting
+	if config.Protocol == SyslogRFC3164 {
+		config.Framing = FramingNewline
+	}
+	if framing, ok := settings["framing"]; ok {
+		if framing != FramingOctetCounting && framing != FramingNewline {
+			return nil, fmt.Errorf("unsupported syslog framing %s, should be octet_counting or newline", framing)
+		}
+		config.Framing = framing
+	}
+
+	var err error
+	if network == "tls" {
+		config.writer, err = newNetWriterWithSettings("tcp", address, true, settings)
+	} else {
+		config.writer, err = newNetWriterWithSettings(network, address, false, settings)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config.asyncEnable, config.asyncBufferSize, config.asyncBlock = getAsyncSettings(settings)
+
+	return newSyslogHook(config), nil
+}
+
+// SyslogHookConfig stores configuration needed to setup the hook
+type SyslogHookConfig struct {
+	Protocol string
+	Framing  string
+	Facility int
+	Hostname string
+	Tag      string
+	MsgID    string
+	SDID     string
+
+	writer *netWriter
+
+	asyncEnable     bool
+	asyncBufferSize int
+	asyncBlock      bool
+}
+
+// SyslogLogHook sends logs to syslog server, like rsyslog/syslog-ng
+type SyslogLogHook struct {
+	writer *netWriter
+
+	protocol string
+	framing  string
+	facility int
+	hostname string
+	tag      string
+	msgID    string
+	sdID     string
+	pid      string
+
+	async *asyncSender
+}
+
+func newSyslogHook(config SyslogHookConfig) *SyslogLogHook {
+	hook := &SyslogLogHook{
+		writer:   config.writer,
+		protocol: config.Protocol,
+		framing:  config.Framing,
+		facility: config.Facility,
+		hostname: config.Hostname,
+		tag:      config.Tag,
+		msgID:    config.MsgID,
+		sdID:     config.SDID,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+
+	if config.asyncEnable {
+		hook.async = newAsyncSender("syslog", config.asyncBufferSize, config.asyncBlock, hook.send)
+	}
+
+	return hook
+}
+
+// Fire is called when a log event is fired.
+func (h *SyslogLogHook) Fire(entry *logrus.Entry) error {
+	if h.async != nil {
+		return h.async.Fire(entry)
+	}
+	return h.send(entry)
+}
+
+func (h *SyslogLogHook) send(entry *logrus.Entry) error {
+	var msg []byte
+	if h.protocol == SyslogRFC3164 {
+		msg = h.formatRFC3164(entry)
+	} else {
+		msg = h.formatRFC5424(entry)
+	}
+
+	if h.writer.isStream() {
+		msg = frame(h.framing, msg)
+	}
+
+	if _, err := h.writer.Write(msg); err != nil {
+		return fmt.Errorf("error sending message to SYSLOG: %s", err)
+	}
+	return nil
+}
+
+// Levels returns the available logging levels.
+func (h *SyslogLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *SyslogLogHook) priority(level logrus.Level) int {
+	return h.facility*8 + syslog.Severity(level)
+}
+
+// formatRFC5424 renders `<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG`
+// the fields of entry will be the params of one SD-ELEMENT
+func (h *SyslogLogHook) formatRFC5424(entry *logrus.Entry) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ",
+		h.priority(entry.Level),
+		entry.Time.Format(rfc5424TimeFormat),
+		syslogHeaderField(h.hostname, 255),
+		syslogHeaderField(h.tag, 48),
+		syslogHeaderField(h.pid, 128),
+		syslogHeaderField(h.msgID, 32),
+	)
+
+	if len(entry.Data) == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteByte('[')
+		b.WriteString(syslogSDName(h.sdID))
+		for _, k := range sortedKeys(entry.Data) {
+			b.WriteByte(' ')
+			b.WriteString(syslogSDName(k))
+			b.WriteString(`="`)
+			b.WriteString(syslogSDValueEscaper.Replace(fmt.Sprint(entry.Data[k])))
+			b.WriteByte('"')
+		}
+		b.WriteByte(']')
+	}
+
+	if entry.Message != "" {
+		b.WriteByte(' ')
+		b.WriteString(entry.Message)
+	}
+	return b.Bytes()
+}
+
+// formatRFC3164 renders `<PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG`, the fields will be appended as `key=value`
+func (h *SyslogLogHook) formatRFC3164(entry *logrus.Entry) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "<%d>%s %s %s[%s]: %s",
+		h.priority(entry.Level),
+		entry.Time.Format(rfc3164TimeFormat),
+		syslogHeaderField(h.hostname, 255),
+		h.tag,
+		h.pid,
+		entry.Message,
+	)
+
+	for _, k := range sortedKeys(entry.Data) {
+		fmt.Fprintf(&b, " %s=%v", k, entry.Data[k])
+	}
+	return b.Bytes()
+}
+
+// parseSyslogFacility accept `local0`, `LOG_LOCAL0` or the facility code
+func parseSyslogFacility(facility string) (int, error) {
+	if code, err := strconv.Atoi(facility); err == nil {
+		if code < 0 || code > 23 {
+			return 0, fmt.Errorf("syslog facility %d out of range [0, 23]", code)
+		}
+		return code, nil
+	}
+
+	name := strings.TrimPrefix(strings.ToLower(facility), "log_")
+	code, ok := syslogFacilities[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog facility %s", facility)
+	}
+	return code, nil
+}
+
+// syslogHeaderField returns NILVALUE for empty value, replace the chars not in PRINTUSASCII and truncate to maxLen
+func syslogHeaderField(value string, maxLen int) string {
+	if value == "" {
+		return "-"
+	}
+	b := []byte(value)
+	for i, c := range b {
+		if c < 33 || c > 126 {
+			b[i] = '_'
+		}
+	}
+	if len(b) > maxLen {
+		b = b[:maxLen]
+	}
+	return string(b)
+}
+
+// syslogSDName returns a valid SD-NAME, 1*32PRINTUSASCII except '=', SP, ']', '"'
+func syslogSDName(name string) string {
+	b := []byte(syslogHeaderField(name, 32))
+	for i, c := range b {
+		if c == '=' || c == ']' || c == '"' {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// the PARAM-VALUE should escape '"', '\' and ']'
+var syslogSDValueEscaper = strings.NewReplacer(`"`, `\"`, `\`, `\\`, `]`, `\]`)
+
+func sortedKeys(data logrus.Fields) []string {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
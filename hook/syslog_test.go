@@ -0,0 +1,246 @@
+package hook
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSyslogHook(t *testing.T) {
+	f := SyslogLogHookBuilder{}
+
+	name := "test"
+
+	var data = []struct {
+		settings  map[string]string
+		willError bool
+	}{
+		// missing host
+		{map[string]string{}, true},
+		// normal, udp will not dial
+		{map[string]string{"host": "127.0.0.1"}, false},
+		{map[string]string{"host": "127.0.0.1", "port": "514", "protocol": "rfc3164", "facility": "LOG_LOCAL0", "tag": "myapp"}, false},
+		{map[string]string{"network": "tcp", "host": "127.0.0.1", "framing": "newline"}, false},
+		{map[string]string{"network": "tls", "host": "127.0.0.1", "tls_insecure_skip_verify": "true"}, false},
+		{map[string]string{"network": "unixgram", "path": "/dev/log"}, false},
+		// unix, missing path
+		{map[string]string{"network": "unix"}, true},
+		// wrong network
+		{map[string]string{"network": "http", "host": "127.0.0.1"}, true},
+		// wrong port
+		{map[string]string{"host": "127.0.0.1", "port": "a"}, true},
+		// wrong protocol
+		{map[string]string{"host": "127.0.0.1", "protocol": "rfc1234"}, true},
+		// wrong facility
+		{map[string]string{"host": "127.0.0.1", "facility": "local9"}, true},
+		// wrong framing
+		{map[string]string{"host": "127.0.0.1", "framing": "length"}, true},
+		// wrong timeout
+		{map[string]string{"host": "127.0.0.1", "write_timeout": "a"}, true},
+		// wrong tls config
+		{map[string]string{"network": "tls", "host": "127.0.0.1", "tls_ca_file": "/not/exists"}, true},
+	}
+	for _, d := range data {
+		_, err := f.New(name, d.settings)
+		if d.willError {
+			assert.Error(t, err, d.settings)
+		} else {
+			assert.NoError(t, err, d.settings)
+		}
+	}
+}
+
+func TestSyslogLogHookLevels(t *testing.T) {
+	h := SyslogLogHook{}
+
+	assert.Len(t, h.Levels(), 7)
+}
+
+func TestParseSyslogFacility(t *testing.T) {
+	var data = []struct {
+		facility  string
+		code      int
+		willError bool
+	}{
+		{"user", 1, false},
+		{"LOG_LOCAL0", 16, false},
+		{"local7", 23, false},
+		{"3", 3, false},
+		{"24", 0, true},
+		{"unknown", 0, true},
+	}
+	for _, d := range data {
+		code, err := parseSyslogFacility(d.facility)
+		if d.willError {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, d.code, code)
+		}
+	}
+}
+
+func newTestSyslogEntry() *logrus.Entry {
+	return &logrus.Entry{
+		Message: "hello world",
+		Level:   logrus.ErrorLevel,
+		Time:    time.Date(2020, 4, 1, 8, 30, 1, 123456789, time.UTC),
+		Data:    logrus.Fields{"b": `say "hi" [x]`, "a": 1},
+	}
+}
+
+func TestSyslogFormatRFC5424(t *testing.T) {
+	h := &SyslogLogHook{
+		facility: 16,
+		hostname: "my host",
+		tag:      "myapp",
+		pid:      "123",
+		sdID:     defaultSyslogSDID,
+	}
+
+	msg := h.formatRFC5424(newTestSyslogEntry())
+	assert.Equal(t,
+		`<131>1 2020-04-01T08:30:01.123456Z my_host myapp 123 - [fields@32473 a="1" b="say \"hi\" [x\]"] hello world`,
+		string(msg))
+
+	// no fields
+	entry := newTestSyslogEntry()
+	entry.Data = logrus.Fields{}
+	msg = h.formatRFC5424(entry)
+	assert.Equal(t, `<131>1 2020-04-01T08:30:01.123456Z my_host myapp 123 - - hello world`, string(msg))
+}
+
+func TestSyslogFormatRFC3164(t *testing.T) {
+	h := &SyslogLogHook{
+		facility: 1,
+		hostname: "localhost",
+		tag:      "myapp",
+		pid:      "123",
+	}
+
+	msg := h.formatRFC3164(newTestSyslogEntry())
+	assert.Equal(t, `<11>Apr  1 08:30:01 localhost myapp[123]: hello world a=1 b=say "hi" [x]`, string(msg))
+}
+
+func TestSyslogLogHookFireUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	host, port, _ := net.SplitHostPort(conn.LocalAddr().String())
+	h, err := SyslogLogHookBuilder{}.New("syslog", map[string]string{
+		"host":         host,
+		"port":         port,
+		"hostname":     "localhost",
+		"tag":          "myapp",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, h.Fire(newTestSyslogEntry()))
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	// datagram, without framing
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "<11>1 2020-04-01T08:30:01.123456Z localhost myapp "))
+	assert.True(t, strings.HasSuffix(string(buf[:n]), "hello world"))
+}
+
+func TestSyslogLogHookFireTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		reader := bufio.NewReader(conn)
+		for {
+			// octet counting: MSG-LEN SP SYSLOG-MSG
+			length, err := reader.ReadString(' ')
+			if err != nil {
+				return
+			}
+			n, _ := strconv.Atoi(strings.TrimSpace(length))
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(reader, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	h, err := SyslogLogHookBuilder{}.New("syslog", map[string]string{
+		"network":      "tcp",
+		"host":         host,
+		"port":         port,
+		"async_enable": "true",
+	})
+	assert.NoError(t, err)
+
+	entry := newTestSyslogEntry()
+	entry.Message = "multi\nline"
+	assert.NoError(t, h.Fire(entry))
+
+	select {
+	case msg := <-received:
+		assert.True(t, strings.HasPrefix(msg, "<11>1 "))
+		assert.True(t, strings.HasSuffix(msg, "multi\nline"))
+	case <-time.After(time.Second):
+		t.Fatal("message not received")
+	}
+}
+
+func TestSyslogLogHookFireUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logging-syslog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "syslog.sock")
+	ln, err := net.Listen("unix", path)
+	assert.NoError(t, err)
+	defer ln.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		scanner := bufio.NewScanner(conn)
+		if scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	h, err := SyslogLogHookBuilder{}.New("syslog", map[string]string{
+		"network":      "unix",
+		"path":         path,
+		"protocol":     "rfc3164",
+		"hostname":     "localhost",
+		"tag":          "myapp",
+		"async_enable": "false",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, h.Fire(newTestSyslogEntry()))
+
+	line := <-lines
+	assert.True(t, strings.HasPrefix(line, "<11>Apr  1 08:30:01 localhost myapp["))
+	assert.True(t, strings.HasSuffix(line, "]: hello world a=1 b=say \"hi\" [x]"))
+}
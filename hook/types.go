@@ -3,6 +3,7 @@ package hook
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -68,3 +69,36 @@ func getAsyncSettings(settings map[string]string) (bool, int, bool) {
 
 	return asyncEnable, asyncBufferSize, asyncBlock
 }
+
+func getBoolSetting(settings map[string]string, key string, defaultValue bool) bool {
+	value, ok := settings[key]
+	if !ok {
+		return defaultValue
+	}
+	return value == "true" || value == "1"
+}
+
+func getIntSetting(settings map[string]string, key string, defaultValue int) (int, error) {
+	value, ok := settings[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s should be integer", key)
+	}
+	return i, nil
+}
+
+// getDurationSetting parse the value like 500ms/5s/1m, see time.ParseDuration
+func getDurationSetting(settings map[string]string, key string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := settings[key]
+	if !ok {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s should be duration, like 500ms, 5s", key)
+	}
+	return d, nil
+}
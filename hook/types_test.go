@@ -2,6 +2,7 @@ package hook
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -33,3 +34,35 @@ func TestGetAsyncSettings(t *testing.T) {
 		assert.Equal(t, d.block, block)
 	}
 }
+
+func TestGetSettings(t *testing.T) {
+	settings := map[string]string{
+		"enable":  "true",
+		"disable": "0",
+		"size":    "10",
+		"bad":     "a",
+		"timeout": "5s",
+	}
+
+	assert.True(t, getBoolSetting(settings, "enable", false))
+	assert.False(t, getBoolSetting(settings, "disable", true))
+	assert.True(t, getBoolSetting(settings, "missing", true))
+
+	i, err := getIntSetting(settings, "size", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, i)
+	i, err = getIntSetting(settings, "missing", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, i)
+	_, err = getIntSetting(settings, "bad", 1)
+	assert.Error(t, err)
+
+	d, err := getDurationSetting(settings, "timeout", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Second, d)
+	d, err = getDurationSetting(settings, "missing", time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, d)
+	_, err = getDurationSetting(settings, "bad", time.Second)
+	assert.Error(t, err)
+}
@@ -0,0 +1,20 @@
+// Package syslog is the syslog helpers shared by the formatters and the hooks
+package syslog
+
+import "github.com/sirupsen/logrus"
+
+// Severity maps the logrus level to syslog severity, same as logrus/hooks/syslog
+func Severity(level logrus.Level) int {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return 2 // crit
+	case logrus.ErrorLevel:
+		return 3 // err
+	case logrus.WarnLevel:
+		return 4 // warning
+	case logrus.InfoLevel:
+		return 6 // info
+	default:
+		return 7 // debug
+	}
+}
@@ -0,0 +1,26 @@
+package syslog
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeverity(t *testing.T) {
+	var data = []struct {
+		level    logrus.Level
+		expected int
+	}{
+		{logrus.PanicLevel, 2},
+		{logrus.FatalLevel, 2},
+		{logrus.ErrorLevel, 3},
+		{logrus.WarnLevel, 4},
+		{logrus.InfoLevel, 6},
+		{logrus.DebugLevel, 7},
+		{logrus.TraceLevel, 7},
+	}
+	for _, d := range data {
+		assert.Equal(t, d.expected, Severity(d.level))
+	}
+}
@@ -0,0 +1,130 @@
+package logging
+
+import (
+	"io"
+	"sort"
+	"sync"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	loggersMu sync.RWMutex
+	loggers   = map[string]*log.Logger{}
+)
+
+// LoggersConfig is the config of the named loggers, e.g. access, api and app,
+// the hooks with the same type and settings are created once and shared by the loggers,
+// and the file hooks of the same path share the opened file, even with different formats
+type LoggersConfig map[string]LogConfig
+
+// NewLoggers creates the named loggers, the loggers created are returned even if some of them fail
+func (c LoggersConfig) NewLoggers() (map[string]*log.Logger, error) {
+	names := make([]string, 0, len(c))
+	for name := range c {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	shared := newSharedHooks()
+	result := make(map[string]*log.Logger, len(c))
+	errs := Errors{}
+	for _, name := range names {
+		logger, err := c[name].newLogger(shared)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "init logger %s fail", name))
+			continue
+		}
+		result[name] = logger
+	}
+
+	if len(errs) != 0 {
+		return result, errors.New(errs.Error())
+	}
+	return result, nil
+}
+
+// Apply creates the named loggers and registers them, which can be got by Get(name)
+func (c LoggersConfig) Apply() error {
+	result, err := c.NewLoggers()
+	for name, logger := range result {
+		Register(name, logger)
+	}
+	return err
+}
+
+// Register registers the logger with the name, the logger registered before with the same name is replaced
+func Register(name string, logger *log.Logger) {
+	loggersMu.Lock()
+	defer loggersMu.Unlock()
+	loggers[name] = logger
+}
+
+// Get returns the logger registered with the name, or the standard logger if not registered
+func Get(name string) *log.Logger {
+	loggersMu.RLock()
+	defer loggersMu.RUnlock()
+	if logger, ok := loggers[name]; ok {
+		return logger
+	}
+	return log.StandardLogger()
+}
+
+// sharedHooks is the hooks keyed by LogConfig.hookKey, and the file writers by path, nil is not shared
+type sharedHooks struct {
+	hooks   map[string]log.Hook
+	writers map[string]io.Writer
+}
+
+func newSharedHooks() *sharedHooks {
+	return &sharedHooks{
+		hooks:   map[string]log.Hook{},
+		writers: map[string]io.Writer{},
+	}
+}
+
+// fileWriters returns the shared file writers, nil if not shared
+func (s *sharedHooks) fileWriters() map[string]io.Writer {
+	if s == nil {
+		return nil
+	}
+	return s.writers
+}
+
+// getOrNew returns the shared hook of the key, or creates and shares it
+func (s *sharedHooks) getOrNew(key string, newHook func() (log.Hook, error)) (log.Hook, error) {
+	if s == nil {
+		return newHook()
+	}
+	if h, ok := s.hooks[key]; ok {
+		return h, nil
+	}
+	h, err := newHook()
+	if err != nil {
+		return nil, err
+	}
+	s.hooks[key] = h
+	return h, nil
+}
+
+// hookKey returns the json of the type and the settings of the hook, with the format for the hooks using
+// the formatter of the logger; the file hooks with different formats are not shared, but the file writer is
+func (c LogConfig) hookKey(h LogHook) string {
+	key := struct {
+		Type           string            `json:"type"`
+		Settings       map[string]string `json:"settings"`
+		Format         LogFormat         `json:"format,omitempty"`
+		FormatSettings map[string]string `json:"format_settings,omitempty"`
+	}{Type: h.Type, Settings: h.Settings}
+
+	switch h.Type {
+	case HookFile, HookNet, HookHTTP, HookKafka:
+		key.Format = c.Format
+		key.FormatSettings = c.FormatSettings
+	}
+	// the keys of the maps are sorted
+	b, _ := jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(key)
+	return string(b)
+}
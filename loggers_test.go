@@ -0,0 +1,98 @@
+package logging
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggersConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "loggers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fileHook := LogHook{Type: HookFile, Settings: map[string]string{"path": dir, "name": "app.log", "async_enable": "false"}}
+	c := LoggersConfig{
+		"api": {Level: "info", Format: JSON, Hooks: LogHooks{fileHook}},
+		"app": {Level: "debug", Format: JSON, Hooks: LogHooks{fileHook}},
+		// the file hook uses the formatter, not shared with the different format, but the file is shared
+		"access": {Level: "info", Format: Logfmt, Hooks: LogHooks{fileHook}},
+	}
+
+	loggers, err := c.NewLoggers()
+	assert.NoError(t, err)
+	if !assert.Len(t, loggers, 3) {
+		return
+	}
+	assert.Equal(t, log.InfoLevel, loggers["api"].Level)
+	assert.Equal(t, log.DebugLevel, loggers["app"].Level)
+
+	api := loggers["api"].Hooks[log.InfoLevel]
+	app := loggers["app"].Hooks[log.InfoLevel]
+	access := loggers["access"].Hooks[log.InfoLevel]
+	if assert.Len(t, api, 1) && assert.Len(t, app, 1) && assert.Len(t, access, 1) {
+		assert.True(t, api[0] == app[0])
+		assert.False(t, api[0] == access[0])
+	}
+
+	loggers["api"].Info("from api")
+	loggers["access"].Info("from access")
+	files, _ := filepath.Glob(filepath.Join(dir, "app.log.*"))
+	if assert.Len(t, files, 1) {
+		content, err := ioutil.ReadFile(files[0])
+		assert.NoError(t, err)
+		assert.Contains(t, string(content), `"msg":"from api"`)
+		assert.Contains(t, string(content), `msg="from access"`)
+	}
+
+	// not shared between the calls
+	again, err := c.NewLoggers()
+	assert.NoError(t, err)
+	assert.False(t, again["api"].Hooks[log.InfoLevel][0] == api[0])
+}
+
+func TestLoggersConfigError(t *testing.T) {
+	c := LoggersConfig{
+		"api": {Level: "info"},
+		"bad": {Level: "unknown"},
+	}
+
+	loggers, err := c.NewLoggers()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "init logger bad fail")
+	assert.Len(t, loggers, 1)
+	assert.Contains(t, loggers, "api")
+}
+
+func TestRegistry(t *testing.T) {
+	assert.Equal(t, log.StandardLogger(), Get("test-registry-none"))
+
+	err := LoggersConfig{"test-registry-api": {Level: "warn"}}.Apply()
+	assert.NoError(t, err)
+	api := Get("test-registry-api")
+	assert.NotEqual(t, log.StandardLogger(), api)
+	assert.Equal(t, log.WarnLevel, api.Level)
+
+	logger := log.New()
+	Register("test-registry-api", logger)
+	assert.Equal(t, logger, Get("test-registry-api"))
+}
+
+func TestHookKey(t *testing.T) {
+	c := LogConfig{Format: JSON, FormatSettings: map[string]string{"sort_keys": "true"}}
+	assert.Equal(t, `{"type":"redis","settings":{"host":"localhost","port":"6379"}}`,
+		c.hookKey(LogHook{Type: HookRedis, Settings: map[string]string{"port": "6379", "host": "localhost"}}))
+	assert.Equal(t, `{"type":"file","settings":{"name":"a.log"},"format":"json","format_settings":{"sort_keys":"true"}}`,
+		c.hookKey(LogHook{Type: HookFile, Settings: map[string]string{"name": "a.log"}}))
+
+	// no collision with the separators in the values
+	assert.NotEqual(t,
+		c.hookKey(LogHook{Type: HookRedis, Settings: map[string]string{"host": "a|port=1"}}),
+		c.hookKey(LogHook{Type: HookRedis, Settings: map[string]string{"host": "a", "port": "1"}}))
+}
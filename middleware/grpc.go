@@ -0,0 +1,414 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	logging "github.com/wklken/logging-go"
+	"github.com/wklken/logging-go/formatter"
+	"github.com/wklken/logging-go/hook"
+)
+
+const (
+	defaultRequestIDMetadataKey = "x-request-id"
+
+	GRPCFieldKind     = "grpc.kind"
+	GRPCFieldService  = "grpc.service"
+	GRPCFieldMethod   = "grpc.method"
+	GRPCFieldCode     = "grpc.code"
+	GRPCFieldRequest  = "grpc.request"
+	GRPCFieldResponse = "grpc.response"
+	GRPCFieldSent     = "grpc.sent"
+	GRPCFieldReceived = "grpc.received"
+	GRPCFieldDuration = "duration_ms"
+	GRPCFieldPeer     = "peer.address"
+)
+
+// GRPCLogConfig is the config of the grpc interceptors
+type GRPCLogConfig struct {
+	// Logger is the logger of the calls, default is the standard logger
+	Logger *logrus.Logger
+
+	// RequestIDMetadataKey is the metadata key of request id, default x-request-id, the id is generated if absent;
+	// the server sets it in the response header, the client sends it from logging.RequestIDFromContext
+	RequestIDMetadataKey string
+
+	// LevelFunc returns the level by the status code, default is DefaultGRPCCodeToLevel
+	LevelFunc func(code codes.Code) logrus.Level
+
+	// SkipMethods are the full methods not logged, e.g. /grpc.health.v1.Health/Check
+	SkipMethods []string
+
+	// LogPayload logs the request and the response of unary calls, and each message of streams
+	LogPayload bool
+	// PayloadMaskFields are the field names masked in the payload, case-insensitive glob, e.g. password, *token*
+	PayloadMaskFields []string
+	// MaxPayloadSize is the max bytes of the payload json, the larger ones are truncated, 0 is no limit;
+	// only the payload is truncated, the other fields are kept
+	MaxPayloadSize int
+}
+
+// DefaultGRPCCodeToLevel: OK is info, the client errors are warning, the server errors are error
+func DefaultGRPCCodeToLevel(code codes.Code) logrus.Level {
+	switch code {
+	case codes.OK:
+		return logrus.InfoLevel
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.PermissionDenied,
+		codes.Unauthenticated, codes.ResourceExhausted, codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return logrus.WarnLevel
+	default:
+		return logrus.ErrorLevel
+	}
+}
+
+type grpcLogger struct {
+	config      GRPCLogConfig
+	skipMethods map[string]struct{}
+	masker      *hook.Redactor
+}
+
+func newGRPCLogger(config GRPCLogConfig) *grpcLogger {
+	if config.Logger == nil {
+		config.Logger = logrus.StandardLogger()
+	}
+	if config.RequestIDMetadataKey == "" {
+		config.RequestIDMetadataKey = defaultRequestIDMetadataKey
+	}
+	if config.LevelFunc == nil {
+		config.LevelFunc = DefaultGRPCCodeToLevel
+	}
+
+	l := &grpcLogger{
+		config:      config,
+		skipMethods: make(map[string]struct{}, len(config.SkipMethods)),
+	}
+	for _, method := range config.SkipMethods {
+		l.skipMethods[method] = struct{}{}
+	}
+	if len(config.PayloadMaskFields) > 0 {
+		masker, err := hook.NewRedactor(map[string]string{
+			"fields":   strings.Join(config.PayloadMaskFields, ","),
+			"patterns": "",
+		})
+		if err != nil {
+			// the payload may be sensitive without the masking, so it's not logged
+			config.Logger.WithError(err).Error("invalid PayloadMaskFields of the grpc interceptor, the payload is not logged")
+			l.config.LogPayload = false
+		}
+		l.masker = masker
+	}
+	return l
+}
+
+// NewUnaryServerInterceptor returns the interceptor which logs the unary calls, and injects the request-scoped entry
+// with the request id into the context, which can be got by logging.FromContext
+func NewUnaryServerInterceptor(config GRPCLogConfig) grpc.UnaryServerInterceptor {
+	l := newGRPCLogger(config)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, entry := l.serverContext(ctx)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(l.config.RequestIDMetadataKey, logging.RequestIDFromContext(ctx)))
+
+		resp, err := handler(ctx, req)
+
+		fields := l.fields("server", info.FullMethod, start, err)
+		if l.config.LogPayload {
+			fields[GRPCFieldRequest] = l.payload(req)
+			if err == nil {
+				fields[GRPCFieldResponse] = l.payload(resp)
+			}
+		}
+		l.log(entry.WithContext(ctx), info.FullMethod, fields, err)
+		return resp, err
+	}
+}
+
+// NewStreamServerInterceptor returns the interceptor which logs the streams when finished, with the messages counts
+func NewStreamServerInterceptor(config GRPCLogConfig) grpc.StreamServerInterceptor {
+	l := newGRPCLogger(config)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, entry := l.serverContext(ss.Context())
+		_ = ss.SetHeader(metadata.Pairs(l.config.RequestIDMetadataKey, logging.RequestIDFromContext(ctx)))
+
+		stream := &loggingServerStream{ServerStream: ss, ctx: ctx, logger: l, entry: entry, method: info.FullMethod}
+		err := handler(srv, stream)
+
+		fields := l.fields("server", info.FullMethod, start, err)
+		fields[GRPCFieldSent] = stream.sent
+		fields[GRPCFieldReceived] = stream.received
+		l.log(entry.WithContext(ctx), info.FullMethod, fields, err)
+		return err
+	}
+}
+
+// NewUnaryClientInterceptor returns the interceptor which logs the unary calls, and sends the request id
+func NewUnaryClientInterceptor(config GRPCLogConfig) grpc.UnaryClientInterceptor {
+	l := newGRPCLogger(config)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, entry := l.clientContext(ctx)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+
+		fields := l.fields("client", method, start, err)
+		fields[GRPCFieldPeer] = cc.Target()
+		if l.config.LogPayload {
+			fields[GRPCFieldRequest] = l.payload(req)
+			if err == nil {
+				fields[GRPCFieldResponse] = l.payload(reply)
+			}
+		}
+		l.log(entry, method, fields, err)
+		return err
+	}
+}
+
+// NewStreamClientInterceptor returns the interceptor which logs the streams when finished, and sends the request id
+func NewStreamClientInterceptor(config GRPCLogConfig) grpc.StreamClientInterceptor {
+	l := newGRPCLogger(config)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string,
+		streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, entry := l.clientContext(ctx)
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			fields := l.fields("client", method, start, err)
+			fields[GRPCFieldPeer] = cc.Target()
+			l.log(entry, method, fields, err)
+			return nil, err
+		}
+		stream := &loggingClientStream{
+			ClientStream: cs, logger: l, entry: entry, method: method, start: start, target: cc.Target(),
+			serverStreams: desc.ServerStreams,
+		}
+		// the stream context is done when the stream is finished, log it if the caller cancels the stream
+		// without reading it to the end
+		go func() {
+			<-cs.Context().Done()
+			if err := ctx.Err(); err != nil {
+				stream.finish(status.FromContextError(err).Err())
+			}
+		}()
+		return stream, nil
+	}
+}
+
+// serverContext returns the context with the request id from metadata or generated, and the request-scoped entry
+func (l *grpcLogger) serverContext(ctx context.Context) (context.Context, *logrus.Entry) {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(l.config.RequestIDMetadataKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+
+	fields := logrus.Fields{formatter.AccessFieldRequestID: requestID}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields[GRPCFieldPeer] = p.Addr.String()
+	}
+	ctx = logging.ContextWithRequestID(ctx, requestID)
+	entry := l.config.Logger.WithFields(fields)
+	return logging.WithContext(ctx, entry), entry
+}
+
+// clientContext returns the context with the request id in the outgoing metadata
+// the request id already in the outgoing metadata is used and not sent twice
+func (l *grpcLogger) clientContext(ctx context.Context) (context.Context, *logrus.Entry) {
+	var requestID string
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(l.config.RequestIDMetadataKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = logging.RequestIDFromContext(ctx)
+		if requestID == "" {
+			requestID = newRequestID()
+			ctx = logging.ContextWithRequestID(ctx, requestID)
+		}
+		ctx = metadata.AppendToOutgoingContext(ctx, l.config.RequestIDMetadataKey, requestID)
+	}
+	return ctx, l.config.Logger.WithField(formatter.AccessFieldRequestID, requestID).WithContext(ctx)
+}
+
+func (l *grpcLogger) fields(kind string, fullMethod string, start time.Time, err error) logrus.Fields {
+	service, method := path.Split(fullMethod)
+	return logrus.Fields{
+		GRPCFieldKind:     kind,
+		GRPCFieldService:  strings.Trim(service, "/"),
+		GRPCFieldMethod:   method,
+		GRPCFieldCode:     status.Code(err).String(),
+		GRPCFieldDuration: float64(time.Since(start).Microseconds()) / 1e3,
+	}
+}
+
+func (l *grpcLogger) log(entry *logrus.Entry, fullMethod string, fields logrus.Fields, err error) {
+	if _, ok := l.skipMethods[fullMethod]; ok {
+		return
+	}
+	entry = entry.WithFields(fields)
+	if err != nil {
+		entry = entry.WithError(err)
+	}
+	entry.Logf(l.config.LevelFunc(status.Code(err)), "grpc %s %s", fields[GRPCFieldKind], fullMethod)
+}
+
+// payload returns the json of the message, with the fields masked and truncated to MaxPayloadSize
+func (l *grpcLogger) payload(msg interface{}) string {
+	return truncatePayload(l.maskedPayload(msg), l.config.MaxPayloadSize)
+}
+
+func (l *grpcLogger) maskedPayload(msg interface{}) string {
+	var b []byte
+	var err error
+	if m, ok := msg.(proto.Message); ok {
+		b, err = protojson.Marshal(m)
+	} else {
+		b, err = jsoniter.Marshal(msg)
+	}
+	if err != nil {
+		return "failed to marshal the payload: " + err.Error()
+	}
+	if l.masker == nil {
+		return string(b)
+	}
+
+	var value interface{}
+	if err := jsoniter.Unmarshal(b, &value); err != nil {
+		return string(b)
+	}
+	entry := &logrus.Entry{Data: logrus.Fields{"payload": value}}
+	l.masker.Redact(entry)
+	b, _ = jsoniter.Marshal(entry.Data["payload"])
+	return string(b)
+}
+
+func (l *grpcLogger) logMessage(entry *logrus.Entry, method string, field string, msg interface{}) {
+	if !l.config.LogPayload {
+		return
+	}
+	if _, ok := l.skipMethods[method]; ok {
+		return
+	}
+	entry.WithField(field, l.payload(msg)).Infof("grpc stream message %s", method)
+}
+
+type loggingServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	logger *grpcLogger
+	entry  *logrus.Entry
+	method string
+
+	sent     int
+	received int
+}
+
+// Context returns the context with the request id and the request-scoped entry
+func (s *loggingServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *loggingServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent++
+		s.logger.logMessage(s.entry.WithContext(s.ctx), s.method, GRPCFieldResponse, m)
+	}
+	return err
+}
+
+func (s *loggingServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		s.received++
+		s.logger.logMessage(s.entry.WithContext(s.ctx), s.method, GRPCFieldRequest, m)
+	}
+	return err
+}
+
+type loggingClientStream struct {
+	grpc.ClientStream
+	logger        *grpcLogger
+	entry         *logrus.Entry
+	method        string
+	start         time.Time
+	target        string
+	serverStreams bool
+
+	sent     int64
+	received int64
+	once     sync.Once
+}
+
+func (s *loggingClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.sent, 1)
+		s.logger.logMessage(s.entry, s.method, GRPCFieldRequest, m)
+	}
+	return err
+}
+
+// RecvMsg logs the stream when it's finished, io.EOF or error, or the response of the client streaming
+func (s *loggingClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.received, 1)
+		s.logger.logMessage(s.entry, s.method, GRPCFieldResponse, m)
+		// the client streaming has only one response, e.g. CloseAndRecv
+		if !s.serverStreams {
+			s.finish(nil)
+		}
+		return nil
+	}
+	s.finish(err)
+	return err
+}
+
+// finish logs the stream once
+func (s *loggingClientStream) finish(err error) {
+	s.once.Do(func() {
+		if err == io.EOF {
+			err = nil
+		}
+		fields := s.logger.fields("client", s.method, s.start, err)
+		fields[GRPCFieldPeer] = s.target
+		fields[GRPCFieldSent] = atomic.LoadInt64(&s.sent)
+		fields[GRPCFieldReceived] = atomic.LoadInt64(&s.received)
+		s.logger.log(s.entry, s.method, fields, err)
+	})
+}
+
+// truncatePayload truncates the payload to n bytes at the rune boundary, 0 is no limit
+func truncatePayload(payload string, n int) string {
+	if n <= 0 || n >= len(payload) {
+		return payload
+	}
+	for n > 0 && !utf8.RuneStart(payload[n]) {
+		n--
+	}
+	return payload[:n] + formatter.TruncatedMarker
+}
@@ -0,0 +1,437 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	logging "github.com/wklken/logging-go"
+	"github.com/wklken/logging-go/formatter"
+)
+
+// syncBuffer is written by the server and the client goroutines
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		data := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &data); err == nil {
+			lines = append(lines, data)
+		}
+	}
+	return lines
+}
+
+func (b *syncBuffer) find(kind string, method string) map[string]interface{} {
+	for _, line := range b.lines() {
+		if line[GRPCFieldKind] == kind && line[GRPCFieldMethod] == method {
+			return line
+		}
+	}
+	return nil
+}
+
+// testCollectServiceDesc is the client streaming service, receives the requests and responds once,
+// the status of the response is the count of the request ids in the metadata
+var testCollectServiceDesc = grpc.ServiceDesc{
+	ServiceName: "test.Collect",
+	HandlerType: (*interface{})(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Collect",
+		ClientStreams: true,
+		Handler: func(_ interface{}, stream grpc.ServerStream) error {
+			for {
+				err := stream.RecvMsg(&healthpb.HealthCheckRequest{})
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+			}
+			md, _ := metadata.FromIncomingContext(stream.Context())
+			resp := &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_ServingStatus(len(md.Get("x-request-id")))}
+			return stream.SendMsg(resp)
+		},
+	}},
+}
+
+type testGRPC struct {
+	server *grpc.Server
+	health *health.Server
+	conn   *grpc.ClientConn
+
+	serverLog *syncBuffer
+	clientLog *syncBuffer
+}
+
+func newTestGRPC(t *testing.T, serverConfig GRPCLogConfig, clientConfig GRPCLogConfig) *testGRPC {
+	g := &testGRPC{serverLog: &syncBuffer{}, clientLog: &syncBuffer{}}
+
+	newLogger := func(buf *syncBuffer) *logrus.Logger {
+		logger := logrus.New()
+		logger.SetOutput(buf)
+		logger.SetFormatter(&formatter.JSONFormatter{})
+		return logger
+	}
+	serverConfig.Logger = newLogger(g.serverLog)
+	clientConfig.Logger = newLogger(g.clientLog)
+
+	listener := bufconn.Listen(1 << 20)
+	g.server = grpc.NewServer(
+		grpc.UnaryInterceptor(NewUnaryServerInterceptor(serverConfig)),
+		grpc.StreamInterceptor(NewStreamServerInterceptor(serverConfig)),
+	)
+	g.health = health.NewServer()
+	healthpb.RegisterHealthServer(g.server, g.health)
+	g.server.RegisterService(&testCollectServiceDesc, nil)
+	go func() {
+		_ = g.server.Serve(listener)
+	}()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(NewUnaryClientInterceptor(clientConfig)),
+		grpc.WithStreamInterceptor(NewStreamClientInterceptor(clientConfig)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.conn = conn
+	return g
+}
+
+func (g *testGRPC) Close() {
+	_ = g.conn.Close()
+	g.server.Stop()
+}
+
+func TestGRPCUnary(t *testing.T) {
+	g := newTestGRPC(t, GRPCLogConfig{}, GRPCLogConfig{})
+	defer g.Close()
+
+	ctx := logging.ContextWithRequestID(context.Background(), "req-1")
+	var header metadata.MD
+	resp, err := healthpb.NewHealthClient(g.conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	assert.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+
+	server := g.serverLog.find("server", "Check")
+	if assert.NotNil(t, server) {
+		assert.Equal(t, "info", server["level"])
+		assert.Equal(t, "grpc server /grpc.health.v1.Health/Check", server["msg"])
+		assert.Equal(t, "grpc.health.v1.Health", server[GRPCFieldService])
+		assert.Equal(t, "OK", server[GRPCFieldCode])
+		assert.Equal(t, "req-1", server["request_id"])
+		assert.Contains(t, server, GRPCFieldDuration)
+		assert.Contains(t, server, GRPCFieldPeer)
+		assert.NotContains(t, server, GRPCFieldRequest)
+	}
+
+	client := g.clientLog.find("client", "Check")
+	if assert.NotNil(t, client) {
+		assert.Equal(t, "info", client["level"])
+		assert.Equal(t, "OK", client[GRPCFieldCode])
+		assert.Equal(t, "req-1", client["request_id"])
+		assert.Equal(t, "bufnet", client[GRPCFieldPeer])
+	}
+}
+
+func TestGRPCUnaryGeneratedRequestID(t *testing.T) {
+	g := newTestGRPC(t, GRPCLogConfig{}, GRPCLogConfig{})
+	defer g.Close()
+
+	_, err := healthpb.NewHealthClient(g.conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+
+	server := g.serverLog.find("server", "Check")
+	client := g.clientLog.find("client", "Check")
+	if assert.NotNil(t, server) && assert.NotNil(t, client) {
+		assert.Len(t, client["request_id"], 32)
+		assert.Equal(t, client["request_id"], server["request_id"])
+	}
+}
+
+func TestGRPCLevelByCode(t *testing.T) {
+	g := newTestGRPC(t, GRPCLogConfig{}, GRPCLogConfig{
+		LevelFunc: func(code codes.Code) logrus.Level {
+			if code == codes.NotFound {
+				return logrus.ErrorLevel
+			}
+			return DefaultGRPCCodeToLevel(code)
+		},
+	})
+	defer g.Close()
+
+	_, err := healthpb.NewHealthClient(g.conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	server := g.serverLog.find("server", "Check")
+	if assert.NotNil(t, server) {
+		assert.Equal(t, "warning", server["level"])
+		assert.Equal(t, "NotFound", server[GRPCFieldCode])
+		assert.Contains(t, server, logrus.ErrorKey)
+	}
+	client := g.clientLog.find("client", "Check")
+	if assert.NotNil(t, client) {
+		assert.Equal(t, "error", client["level"])
+	}
+}
+
+func TestDefaultGRPCCodeToLevel(t *testing.T) {
+	assert.Equal(t, logrus.InfoLevel, DefaultGRPCCodeToLevel(codes.OK))
+	assert.Equal(t, logrus.WarnLevel, DefaultGRPCCodeToLevel(codes.InvalidArgument))
+	assert.Equal(t, logrus.WarnLevel, DefaultGRPCCodeToLevel(codes.Canceled))
+	assert.Equal(t, logrus.ErrorLevel, DefaultGRPCCodeToLevel(codes.Internal))
+	assert.Equal(t, logrus.ErrorLevel, DefaultGRPCCodeToLevel(codes.Unavailable))
+	assert.Equal(t, logrus.ErrorLevel, DefaultGRPCCodeToLevel(codes.Unknown))
+}
+
+func TestGRPCPayload(t *testing.T) {
+	config := GRPCLogConfig{LogPayload: true, PayloadMaskFields: []string{"service"}}
+	g := newTestGRPC(t, config, config)
+	defer g.Close()
+
+	g.health.SetServingStatus("secret", healthpb.HealthCheckResponse_SERVING)
+	_, err := healthpb.NewHealthClient(g.conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "secret"})
+	assert.NoError(t, err)
+
+	for _, line := range []map[string]interface{}{g.serverLog.find("server", "Check"), g.clientLog.find("client", "Check")} {
+		if assert.NotNil(t, line) {
+			assert.JSONEq(t, `{"service":"[REDACTED]"}`, line[GRPCFieldRequest].(string))
+			assert.JSONEq(t, `{"status":"SERVING"}`, line[GRPCFieldResponse].(string))
+		}
+	}
+}
+
+func TestGRPCPayloadTruncated(t *testing.T) {
+	g := newTestGRPC(t, GRPCLogConfig{LogPayload: true, MaxPayloadSize: 20}, GRPCLogConfig{})
+	defer g.Close()
+
+	service := strings.Repeat("a", 100)
+	g.health.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+	_, err := healthpb.NewHealthClient(g.conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	assert.NoError(t, err)
+
+	server := g.serverLog.find("server", "Check")
+	if assert.NotNil(t, server) {
+		assert.Equal(t, `{"service":"aaaaaaaa`+formatter.TruncatedMarker, server[GRPCFieldRequest])
+		// only the payload is truncated
+		assert.Equal(t, `{"status":"SERVING"}`, server[GRPCFieldResponse])
+		assert.Len(t, server["request_id"], 32)
+		assert.Equal(t, "Check", server[GRPCFieldMethod])
+		assert.NotContains(t, server, formatter.FieldKeyTruncated)
+	}
+}
+
+func TestGRPCNotTruncatedWithoutPayload(t *testing.T) {
+	g := newTestGRPC(t, GRPCLogConfig{MaxPayloadSize: 5}, GRPCLogConfig{})
+	defer g.Close()
+
+	_, err := healthpb.NewHealthClient(g.conn).Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	server := g.serverLog.find("server", "Check")
+	if assert.NotNil(t, server) {
+		assert.Len(t, server["request_id"], 32)
+		assert.Equal(t, "rpc error: code = NotFound desc = unknown service", server[logrus.ErrorKey])
+		assert.NotContains(t, server, formatter.FieldKeyTruncated)
+	}
+}
+
+func TestGRPCInvalidPayloadMaskFields(t *testing.T) {
+	config := GRPCLogConfig{LogPayload: true, PayloadMaskFields: []string{"["}}
+	g := newTestGRPC(t, config, GRPCLogConfig{})
+	defer g.Close()
+
+	_, err := healthpb.NewHealthClient(g.conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+
+	lines := g.serverLog.lines()
+	if assert.Len(t, lines, 3) {
+		// the unary and the stream interceptors
+		assert.Contains(t, lines[0]["msg"], "invalid PayloadMaskFields")
+		assert.Contains(t, lines[1]["msg"], "invalid PayloadMaskFields")
+		assert.NotContains(t, lines[2], GRPCFieldRequest)
+		assert.Equal(t, "OK", lines[2][GRPCFieldCode])
+	}
+}
+
+func TestGRPCRequestIDInOutgoingMetadata(t *testing.T) {
+	g := newTestGRPC(t, GRPCLogConfig{}, GRPCLogConfig{})
+	defer g.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-3")
+	ctx = logging.ContextWithRequestID(ctx, "req-3")
+	stream, err := g.conn.NewStream(ctx, &testCollectServiceDesc.Streams[0], "/test.Collect/Collect")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, stream.CloseSend())
+	resp := &healthpb.HealthCheckResponse{}
+	assert.NoError(t, stream.RecvMsg(resp))
+
+	// the request id is sent once
+	assert.EqualValues(t, 1, resp.Status)
+	client := g.clientLog.find("client", "Collect")
+	if assert.NotNil(t, client) {
+		assert.Equal(t, "req-3", client["request_id"])
+	}
+}
+
+func TestGRPCClientStream(t *testing.T) {
+	config := GRPCLogConfig{LogPayload: true}
+	g := newTestGRPC(t, config, config)
+	defer g.Close()
+
+	stream, err := g.conn.NewStream(context.Background(), &testCollectServiceDesc.Streams[0], "/test.Collect/Collect")
+	if !assert.NoError(t, err) {
+		return
+	}
+	for _, service := range []string{"a", "b", "c"} {
+		assert.NoError(t, stream.SendMsg(&healthpb.HealthCheckRequest{Service: service}))
+	}
+	assert.NoError(t, stream.CloseSend())
+	resp := &healthpb.HealthCheckResponse{}
+	assert.NoError(t, stream.RecvMsg(resp))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// logged once the response is received, without reading to io.EOF
+	client := g.clientLog.find("client", "Collect")
+	if assert.NotNil(t, client) {
+		assert.Equal(t, "info", client["level"])
+		assert.Equal(t, "OK", client[GRPCFieldCode])
+		assert.EqualValues(t, 3, client[GRPCFieldSent])
+		assert.EqualValues(t, 1, client[GRPCFieldReceived])
+	}
+
+	var server map[string]interface{}
+	for i := 0; i < 100 && server == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		server = g.serverLog.find("server", "Collect")
+	}
+	if assert.NotNil(t, server) {
+		assert.Equal(t, client["request_id"], server["request_id"])
+		assert.EqualValues(t, 3, server[GRPCFieldReceived])
+		assert.EqualValues(t, 1, server[GRPCFieldSent])
+	}
+}
+
+func TestGRPCServerStreamNotRead(t *testing.T) {
+	g := newTestGRPC(t, GRPCLogConfig{}, GRPCLogConfig{})
+	defer g.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := healthpb.NewHealthClient(g.conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	// the caller stops without reading
+	cancel()
+
+	var client map[string]interface{}
+	for i := 0; i < 100 && client == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		client = g.clientLog.find("client", "Watch")
+	}
+	if assert.NotNil(t, client) {
+		assert.Equal(t, "Canceled", client[GRPCFieldCode])
+		assert.EqualValues(t, 0, client[GRPCFieldReceived])
+	}
+}
+
+func TestGRPCSkipMethods(t *testing.T) {
+	config := GRPCLogConfig{SkipMethods: []string{"/grpc.health.v1.Health/Check"}}
+	g := newTestGRPC(t, config, config)
+	defer g.Close()
+
+	_, err := healthpb.NewHealthClient(g.conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+
+	assert.Empty(t, g.serverLog.lines())
+	assert.Empty(t, g.clientLog.lines())
+}
+
+func TestGRPCStream(t *testing.T) {
+	config := GRPCLogConfig{LogPayload: true}
+	g := newTestGRPC(t, config, config)
+	defer g.Close()
+
+	ctx, cancel := context.WithCancel(logging.ContextWithRequestID(context.Background(), "req-2"))
+	stream, err := healthpb.NewHealthClient(g.conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if !assert.NoError(t, err) {
+		cancel()
+		return
+	}
+	resp, err := stream.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Canceled, status.Code(err))
+
+	client := g.clientLog.find("client", "Watch")
+	if assert.NotNil(t, client) {
+		assert.Equal(t, "warning", client["level"])
+		assert.Equal(t, "Canceled", client[GRPCFieldCode])
+		assert.Equal(t, "req-2", client["request_id"])
+		assert.EqualValues(t, 1, client[GRPCFieldSent])
+		assert.EqualValues(t, 1, client[GRPCFieldReceived])
+	}
+
+	// the server finishes the stream asynchronously after the cancel
+	var server map[string]interface{}
+	for i := 0; i < 100 && server == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+		server = g.serverLog.find("server", "Watch")
+	}
+	if assert.NotNil(t, server) {
+		assert.Equal(t, "req-2", server["request_id"])
+		assert.EqualValues(t, 1, server[GRPCFieldReceived])
+		assert.EqualValues(t, 1, server[GRPCFieldSent])
+	}
+
+	messages := 0
+	for _, line := range g.serverLog.lines() {
+		if line["msg"] == "grpc stream message /grpc.health.v1.Health/Watch" {
+			messages++
+			assert.Equal(t, "req-2", line["request_id"])
+		}
+	}
+	assert.Equal(t, 2, messages)
+}
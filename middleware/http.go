@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	logging "github.com/wklken/logging-go"
+	"github.com/wklken/logging-go/formatter"
+)
+
+const defaultRequestIDHeader = "X-Request-Id"
+
+// AccessLogConfig is the config of the access log middleware
+type AccessLogConfig struct {
+	// Logger is the logger of access log, e.g. from LogConfig.NewLogger with the format `access`,
+	// default is the standard logger
+	Logger *logrus.Logger
+
+	// FieldNames is the custom names of the fields, e.g. {"remote_ip": "client_ip"}, see formatter.AccessFieldXXX
+	FieldNames map[string]string
+
+	// SkipPaths are not logged, e.g. /healthz, /metrics
+	SkipPaths []string
+
+	// SlowThreshold promotes the requests slower than it to warning, 0 is disabled
+	SlowThreshold time.Duration
+
+	// RequestIDHeader is the header of request id, default X-Request-Id, the id is generated if absent,
+	// and set in the response header
+	RequestIDHeader string
+
+	// TrustProxy uses the X-Forwarded-For or X-Real-IP as the remote ip, only enable it behind the proxy
+	TrustProxy bool
+}
+
+// NewAccessLogMiddleware returns the middleware which logs the access of each request, and injects the
+// request-scoped entry with the request id into the request context, which can be got by logging.FromContext
+func NewAccessLogMiddleware(config AccessLogConfig) func(http.Handler) http.Handler {
+	if config.Logger == nil {
+		config.Logger = logrus.StandardLogger()
+	}
+	if config.RequestIDHeader == "" {
+		config.RequestIDHeader = defaultRequestIDHeader
+	}
+
+	skipPaths := make(map[string]struct{}, len(config.SkipPaths))
+	for _, path := range config.SkipPaths {
+		skipPaths[path] = struct{}{}
+	}
+
+	field := func(name string) string {
+		if custom, ok := config.FieldNames[name]; ok {
+			return custom
+		}
+		return name
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(config.RequestIDHeader)
+			if requestID == "" {
+				requestID = newRequestID()
+			}
+			w.Header().Set(config.RequestIDHeader, requestID)
+
+			ctx := logging.ContextWithRequestID(r.Context(), requestID)
+			entry := config.Logger.WithField(field(formatter.AccessFieldRequestID), requestID)
+			ctx = logging.WithContext(ctx, entry)
+			r = r.WithContext(ctx)
+
+			rw := &responseWriter{ResponseWriter: w}
+			next.ServeHTTP(rw, r)
+
+			if _, ok := skipPaths[r.URL.Path]; ok {
+				return
+			}
+
+			duration := time.Since(start)
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			fields := logrus.Fields{
+				field(formatter.AccessFieldMethod):    r.Method,
+				field(formatter.AccessFieldPath):      r.URL.Path,
+				field(formatter.AccessFieldProto):     r.Proto,
+				field(formatter.AccessFieldStatus):    status,
+				field(formatter.AccessFieldBytes):     rw.bytes,
+				field(formatter.AccessFieldDuration):  float64(duration.Microseconds()) / 1e3,
+				field(formatter.AccessFieldRemoteIP):  remoteIP(r, config.TrustProxy),
+				field(formatter.AccessFieldUserAgent): r.UserAgent(),
+			}
+			if r.URL.RawQuery != "" {
+				fields[field(formatter.AccessFieldQuery)] = r.URL.RawQuery
+			}
+			if referer := r.Referer(); referer != "" {
+				fields[field(formatter.AccessFieldReferer)] = referer
+			}
+			if user, _, ok := r.BasicAuth(); ok {
+				fields[field(formatter.AccessFieldUser)] = user
+			}
+
+			level := logrus.InfoLevel
+			if config.SlowThreshold > 0 && duration >= config.SlowThreshold {
+				level = logrus.WarnLevel
+			}
+			entry.WithContext(ctx).WithFields(fields).Logf(level, "%s %s %d", r.Method, r.URL.Path, status)
+		})
+	}
+}
+
+// responseWriter records the status and the bytes written
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher if the underlying writer supports it
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the underlying writer supports it
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("the response writer does not support hijack")
+	}
+	if w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return h.Hijack()
+}
+
+// remoteIP returns the ip of the client, the first of X-Forwarded-For or X-Real-IP if trust the proxy
+func remoteIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return realIP
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
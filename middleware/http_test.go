@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	logging "github.com/wklken/logging-go"
+	"github.com/wklken/logging-go/formatter"
+)
+
+func newTestAccessLogger(buf *bytes.Buffer, f logrus.Formatter) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(buf)
+	logger.SetFormatter(f)
+	return logger
+}
+
+func TestAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestAccessLogger(&buf, &formatter.JSONFormatter{})
+
+	var requestID string
+	handler := NewAccessLogMiddleware(AccessLogConfig{Logger: logger})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the request-scoped entry
+		entry := logging.FromContext(r.Context())
+		requestID, _ = entry.Data["request_id"].(string)
+		entry.Info("in handler")
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users?a=1", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set("Referer", "http://a.com/")
+	req.SetBasicAuth("tom", "pass")
+	req.RemoteAddr = "10.0.0.1:12345"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Len(t, requestID, 32)
+	assert.Equal(t, requestID, rec.Header().Get("X-Request-Id"))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !assert.Len(t, lines, 2) {
+		return
+	}
+	assert.Contains(t, lines[0], `"msg":"in handler"`)
+	assert.Contains(t, lines[0], `"request_id":"`+requestID+`"`)
+
+	data := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &data))
+	assert.Equal(t, "info", data["level"])
+	assert.Equal(t, "POST /api/users 201", data["msg"])
+	assert.Equal(t, "POST", data["method"])
+	assert.Equal(t, "/api/users", data["path"])
+	assert.Equal(t, "a=1", data["query"])
+	assert.Equal(t, "HTTP/1.1", data["proto"])
+	assert.Equal(t, float64(201), data["status"])
+	assert.Equal(t, float64(5), data["bytes"])
+	assert.Equal(t, "10.0.0.1", data["remote_ip"])
+	assert.Equal(t, "test-agent", data["user_agent"])
+	assert.Equal(t, "http://a.com/", data["referer"])
+	assert.Equal(t, "tom", data["user"])
+	assert.Equal(t, requestID, data["request_id"])
+	assert.Contains(t, data, "duration_ms")
+}
+
+func TestAccessLogMiddlewareOptions(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestAccessLogger(&buf, &formatter.AccessLogFormatter{
+		Style:      formatter.AccessLogCommon,
+		FieldNames: map[string]string{formatter.AccessFieldRemoteIP: "client_ip"},
+	})
+
+	handler := NewAccessLogMiddleware(AccessLogConfig{
+		Logger:          logger,
+		FieldNames:      map[string]string{formatter.AccessFieldRemoteIP: "client_ip"},
+		SkipPaths:       []string{"/healthz"},
+		SlowThreshold:   10 * time.Millisecond,
+		RequestIDHeader: "X-Trace",
+		TrustProxy:      true,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(20 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	// skipped
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, "", buf.String())
+	assert.NotEmpty(t, rec.Header().Get("X-Trace"))
+
+	// the request id from header, the remote ip from the proxy headers
+	req := httptest.NewRequest(http.MethodGet, "/api", nil)
+	req.Header.Set("X-Trace", "trace1")
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "trace1", rec.Header().Get("X-Trace"))
+	assert.True(t, strings.HasPrefix(buf.String(), "1.1.1.1 - - ["), buf.String())
+	assert.True(t, strings.HasSuffix(buf.String(), `] "GET /api HTTP/1.1" 200 2`+"\n"), buf.String())
+
+	// slow request is warning
+	var levels []logrus.Level
+	logger.AddHook(&testLevelHook{levels: &levels})
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, []logrus.Level{logrus.WarnLevel, logrus.InfoLevel}, levels)
+}
+
+type testLevelHook struct {
+	levels *[]logrus.Level
+}
+
+func (h *testLevelHook) Fire(entry *logrus.Entry) error {
+	*h.levels = append(*h.levels, entry.Level)
+	return nil
+}
+
+func (h *testLevelHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func TestRemoteIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "10.0.0.1:80"
+	req.Header.Set("X-Real-IP", "3.3.3.3")
+	assert.Equal(t, "10.0.0.1", remoteIP(req, false))
+	assert.Equal(t, "3.3.3.3", remoteIP(req, true))
+
+	req.RemoteAddr = "unix"
+	assert.Equal(t, "unix", remoteIP(req, false))
+}
+
+func TestResponseWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := &responseWriter{ResponseWriter: rec}
+	w.Flush()
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, 0, w.status)
+
+	_, _, err := w.Hijack()
+	assert.Error(t, err)
+
+	_, _ = w.Write([]byte("a"))
+	w.WriteHeader(http.StatusNotFound)
+	assert.Equal(t, http.StatusOK, w.status)
+	assert.Equal(t, 1, w.bytes)
+}
@@ -0,0 +1,133 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"context"
+	"log/slog"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SlogHandler is the slog.Handler backed by the logger, e.g. from LogConfig.NewLogger,
+// so the entries of slog go through the hooks and the formatter of the logger;
+// the attributes are the fields, the keys in groups are joined by `.`, e.g. `request.method`
+type SlogHandler struct {
+	logger *log.Logger
+	fields log.Fields
+	prefix string
+}
+
+// NewSlogHandler returns the slog.Handler backed by the logger, use slog.New(handler) or slog.SetDefault to use it
+func NewSlogHandler(logger *log.Logger) *SlogHandler {
+	return &SlogHandler{logger: logger}
+}
+
+// SlogLevel returns the logrus level of the slog level, the levels between are rounded down,
+// e.g. slog.LevelInfo+2 is info, and the levels lower than debug are trace
+func SlogLevel(level slog.Level) log.Level {
+	switch {
+	case level >= slog.LevelError:
+		return log.ErrorLevel
+	case level >= slog.LevelWarn:
+		return log.WarnLevel
+	case level >= slog.LevelInfo:
+		return log.InfoLevel
+	case level >= slog.LevelDebug:
+		return log.DebugLevel
+	default:
+		return log.TraceLevel
+	}
+}
+
+// Enabled reports whether the logger is enabled at the level
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.IsLevelEnabled(SlogLevel(level))
+}
+
+// Handle logs the record with the attributes as the fields
+func (h *SlogHandler) Handle(ctx context.Context, record slog.Record) error {
+	fields := make(log.Fields, len(h.fields)+record.NumAttrs())
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	record.Attrs(func(attr slog.Attr) bool {
+		addSlogAttr(fields, h.prefix, attr)
+		return true
+	})
+
+	entry := h.logger.WithFields(fields)
+	if ctx != nil {
+		entry = entry.WithContext(ctx)
+	}
+	if !record.Time.IsZero() {
+		entry = entry.WithTime(record.Time)
+	}
+	entry.Log(SlogLevel(record.Level), record.Message)
+	return nil
+}
+
+// WithAttrs returns a new handler with the attributes added to all the records
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	fields := make(log.Fields, len(h.fields)+len(attrs))
+	for k, v := range h.fields {
+		fields[k] = v
+	}
+	for _, attr := range attrs {
+		addSlogAttr(fields, h.prefix, attr)
+	}
+	return &SlogHandler{logger: h.logger, fields: fields, prefix: h.prefix}
+}
+
+// WithGroup returns a new handler with the group, the keys of the attributes after it are prefixed by the name
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &SlogHandler{logger: h.logger, fields: h.fields, prefix: h.prefix + name + "."}
+}
+
+// addSlogAttr adds the attribute to the fields, the group is flattened with the prefix,
+// the empty attributes are ignored, and the groups without key are inlined, same as slog.JSONHandler
+func addSlogAttr(fields log.Fields, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+		for _, a := range attr.Value.Group() {
+			addSlogAttr(fields, prefix, a)
+		}
+		return
+	}
+	fields[prefix+attr.Key] = slogValue(attr.Value)
+}
+
+func slogValue(value slog.Value) interface{} {
+	switch value.Kind() {
+	case slog.KindString:
+		return value.String()
+	case slog.KindInt64:
+		return value.Int64()
+	case slog.KindUint64:
+		return value.Uint64()
+	case slog.KindFloat64:
+		return value.Float64()
+	case slog.KindBool:
+		return value.Bool()
+	case slog.KindDuration:
+		return value.Duration()
+	case slog.KindTime:
+		return value.Time()
+	default:
+		return value.Any()
+	}
+}
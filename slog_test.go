@@ -0,0 +1,108 @@
+//go:build go1.21
+// +build go1.21
+
+package logging
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testSlogValuer struct{}
+
+func (testSlogValuer) LogValue() slog.Value {
+	return slog.StringValue("resolved")
+}
+
+func newTestSlogLogger(level log.Level) (*log.Logger, *testContextRecorder) {
+	logger := log.New()
+	logger.SetOutput(&bytes.Buffer{})
+	logger.SetLevel(level)
+	recorder := &testContextRecorder{}
+	logger.AddHook(recorder)
+	return logger, recorder
+}
+
+func TestSlogLevel(t *testing.T) {
+	assert.Equal(t, log.TraceLevel, SlogLevel(slog.LevelDebug-1))
+	assert.Equal(t, log.DebugLevel, SlogLevel(slog.LevelDebug))
+	assert.Equal(t, log.InfoLevel, SlogLevel(slog.LevelInfo))
+	assert.Equal(t, log.InfoLevel, SlogLevel(slog.LevelInfo+2))
+	assert.Equal(t, log.WarnLevel, SlogLevel(slog.LevelWarn))
+	assert.Equal(t, log.ErrorLevel, SlogLevel(slog.LevelError))
+	assert.Equal(t, log.ErrorLevel, SlogLevel(slog.LevelError+4))
+}
+
+func TestSlogHandler(t *testing.T) {
+	logger, recorder := newTestSlogLogger(log.InfoLevel)
+	l := slog.New(NewSlogHandler(logger))
+
+	err := errors.New("boom")
+	l.With("app", "demo").WithGroup("request").With("method", "GET").Warn("hello",
+		"status", 200,
+		"elapsed", time.Second,
+		"ok", true,
+		slog.Group("user", "id", uint64(1), "name", "tom"),
+		slog.Group("", "inline", 1.5),
+		slog.Any("valuer", testSlogValuer{}),
+		slog.Any("error", err),
+		slog.Attr{},
+	)
+	l.Debug("dropped")
+
+	if !assert.Len(t, recorder.entries, 1) {
+		return
+	}
+	entry := recorder.entries[0]
+	assert.Equal(t, log.WarnLevel, entry.Level)
+	assert.Equal(t, "hello", entry.Message)
+	assert.Equal(t, log.Fields{
+		"app":               "demo",
+		"request.method":    "GET",
+		"request.status":    int64(200),
+		"request.elapsed":   time.Second,
+		"request.ok":        true,
+		"request.user.id":   uint64(1),
+		"request.user.name": "tom",
+		"request.inline":    1.5,
+		"request.valuer":    "resolved",
+		"request.error":     err,
+	}, entry.Data)
+}
+
+func TestSlogHandlerContext(t *testing.T) {
+	logger, recorder := newTestSlogLogger(log.InfoLevel)
+	logger.AddHook(&contextHook{extractors: DefaultContextExtractors})
+	l := slog.New(NewSlogHandler(logger))
+
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	record := slog.NewRecord(now, slog.LevelError, "failed", 0)
+	record.AddAttrs(slog.String("a", "b"))
+	ctx := ContextWithRequestID(context.Background(), "req1")
+	assert.NoError(t, l.Handler().Handle(ctx, record))
+
+	if !assert.Len(t, recorder.entries, 1) {
+		return
+	}
+	entry := recorder.entries[0]
+	assert.Equal(t, log.ErrorLevel, entry.Level)
+	assert.Equal(t, now, entry.Time)
+	assert.Equal(t, "b", entry.Data["a"])
+	assert.Equal(t, "req1", entry.Data[FieldKeyRequestID])
+}
+
+func TestSlogHandlerEnabled(t *testing.T) {
+	logger, _ := newTestSlogLogger(log.WarnLevel)
+	h := NewSlogHandler(logger)
+	assert.False(t, h.Enabled(context.Background(), slog.LevelInfo))
+	assert.True(t, h.Enabled(context.Background(), slog.LevelWarn))
+	assert.Equal(t, h, h.WithGroup(""))
+	assert.Equal(t, h, h.WithAttrs(nil))
+}
@@ -0,0 +1,38 @@
+package logging
+
+import (
+	stdlog "log"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RedirectStdLog redirects the output of the standard library log to the logger at the level,
+// so the entries of log.Printf go through the hooks and the formatter of the logger;
+// the flags and the prefix are cleared, the returned func restores them and the output
+func RedirectStdLog(logger *log.Logger, level log.Level) func() {
+	flags := stdlog.Flags()
+	prefix := stdlog.Prefix()
+	writer := stdlog.Writer()
+
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	stdlog.SetOutput(&stdLogWriter{logger: logger, level: level})
+
+	return func() {
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+		stdlog.SetOutput(writer)
+	}
+}
+
+// stdLogWriter logs each write of the standard library log as an entry
+type stdLogWriter struct {
+	logger *log.Logger
+	level  log.Level
+}
+
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	w.logger.Log(w.level, strings.TrimRight(string(p), "\r\n"))
+	return len(p), nil
+}
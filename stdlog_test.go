@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	stdlog "log"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedirectStdLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&buf)
+	recorder := &testContextRecorder{}
+	logger.AddHook(recorder)
+
+	stdlog.SetPrefix("old: ")
+	restore := RedirectStdLog(logger, log.WarnLevel)
+	stdlog.Printf("hello %s", "world")
+	stdlog.Println("second")
+	restore()
+
+	if assert.Len(t, recorder.entries, 2) {
+		assert.Equal(t, log.WarnLevel, recorder.entries[0].Level)
+		assert.Equal(t, "hello world", recorder.entries[0].Message)
+		assert.Equal(t, "second", recorder.entries[1].Message)
+	}
+	assert.Contains(t, buf.String(), `msg="hello world"`)
+
+	// restored
+	assert.Equal(t, "old: ", stdlog.Prefix())
+	assert.NotEqual(t, 0, stdlog.Flags())
+	stdlog.SetPrefix("")
+	stdlog.Print("not redirected")
+	assert.Len(t, recorder.entries, 2)
+}
+
+func TestRedirectStdLogLevel(t *testing.T) {
+	logger := log.New()
+	logger.SetOutput(&bytes.Buffer{})
+	logger.SetLevel(log.InfoLevel)
+	recorder := &testContextRecorder{}
+	logger.AddHook(recorder)
+
+	restore := RedirectStdLog(logger, log.DebugLevel)
+	defer restore()
+	stdlog.Print("dropped")
+	assert.Empty(t, recorder.entries)
+}